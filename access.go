@@ -0,0 +1,127 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// how much is said about a room when the player walks into it
+const (
+	Brief      = iota // the long description the first time, and the short one after
+	Verbose           // the long description every time
+	Superbrief        // just the name of the room
+)
+
+var accessible bool    // the game is being played with a screen reader
+var verbosity = Brief  // how much is said about a room on entering it
+var lastMessage string // what the game said in answer to the last command
+
+// repeatMessage says again what the game said in answer to the last command
+func repeatMessage() {
+	if lastMessage == "" {
+		say("nothing-to-repeat", "There's nothing to repeat yet.")
+		return
+	}
+
+	narrate(strings.TrimSuffix(lastMessage, "\n"))
+}
+
+// setVerbosity changes how much is said about a room on entering it
+func setVerbosity(mode string) {
+	switch mode {
+	case "verbose":
+		verbosity = Verbose
+		say("verbose-mode", "Rooms will be described in full every time you enter them.")
+	case "brief":
+		verbosity = Brief
+		say("brief-mode", "Rooms will be described in full the first time you enter them.")
+	case "superbrief":
+		verbosity = Superbrief
+		say("superbrief-mode", "Only the names of rooms will be given when you enter them.")
+	}
+}
+
+// sentence makes 'm' start with a capital letter, as a sentence should, even
+// when it starts with the name of an item
+func sentence(m string) string {
+	i := strings.IndexFunc(m, func(c rune) bool { return !unicode.IsSpace(c) })
+	if i < 0 {
+		return m
+	}
+
+	c, n := utf8.DecodeRuneInString(m[i:])
+	return m[:i] + string(unicode.ToUpper(c)) + m[i+n:]
+}
+
+// spokenList joins 'names' the way they'd be said aloud: "a, b and c"
+func spokenList(names []string) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	}
+
+	return text("list-and", "%s and %s", strings.Join(names[:len(names)-1], ", "), names[len(names)-1])
+}
+
+// theNames puts "the" in front of each of 'names' in the player's language,
+// in alphabetical order
+func theNames(names []string) []string {
+	var the []string
+	for _, name := range names {
+		the = append(the, text("the-name", "the %s", name))
+	}
+	sort.Strings(the)
+
+	return the
+}
+
+// sayContents says what's inside the open container 'val', in a sentence
+func sayContents(val *Item) {
+	var names []string
+	for name := range val.Contents {
+		names = append(names, name)
+	}
+
+	say("inside-the-spoken", "Inside the %s you see %s.", val.Name, spokenList(theNames(names)))
+}
+
+// sayFloor describes the 'shown' rooms on 'floor' in sentences instead of
+// drawing them, with the rooms each one leads to on the same floor, and
+// whether there's a way up or down
+func sayFloor(floor int, shown map[string]bool, linked map[[2]string]bool, up map[string]bool, down map[string]bool) {
+	var names []string
+	for name := range shown {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	say("you-are-on", "You are in the %s, on %s.", curRoom.Name, floorName(floor))
+	for _, name := range names {
+		var to []string
+		for _, other := range names {
+			if linked[[2]string{name, other}] {
+				to = append(to, text("the-name", "the %s", other))
+			}
+		}
+
+		switch {
+		case !rooms[name].Visited:
+			say("you-havent-been-to", "You haven't been to the %s yet.", name)
+		case len(to) > 0:
+			say("leads-to", "The %s leads to %s.", name, spokenList(to))
+		default:
+			say("leads-nowhere", "The %s doesn't lead anywhere else on this floor.", name)
+		}
+
+		if up[name] {
+			say("way-up-from", "There's a way up from the %s.", name)
+		}
+		if down[name] {
+			say("way-down-from", "There's a way down from the %s.", name)
+		}
+	}
+}
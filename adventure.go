@@ -3,13 +3,20 @@ package main
 import (
 	"bufio"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"math/rand"
 	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
+
+	"golang.org/x/term"
 )
 
 // Some necessary globals
@@ -17,12 +24,17 @@ const MinRooms = 15
 const MinItems = 8
 
 var rooms = make(map[string]*Room)        // map of rooms
+var world map[string]*Room                // rooms as they are when a game begins
+var catalog = make(map[string]*Item)      // every item as it is when a game begins
 var roomAliases = make(map[string]string) // map of room name aliases
 var inventory = make(map[string]*Item)    // player inventory
 var curRoom *Room
 var gameOver bool
 var climbedUp bool
-var eagleWatching bool
+var turns int                                              // how many turns the player has taken
+var out io.Writer = os.Stdout                              // destination for all game text
+var in = bufio.NewScanner(os.Stdin)                        // source of all player input
+var dice = rand.New(rand.NewSource(time.Now().UnixNano())) // chance, for the NPCs
 
 // definition of a room
 type Room struct {
@@ -33,30 +45,87 @@ type Room struct {
 	Items       map[string]*Item
 	Visited     bool
 	Exits       []string // outbound connection room names
-	ExitItems   []string // items required to exit a room
-	ExitBlock   string   // describe why the player cannot exit a room
+	Passages    []*Exit  // exits with directions, aliases, or more than a walk to get through
+	Layout      *Layout  // where the room is drawn on the map; rooms without one go beside a neighbour
+	Light       string   // Dim or Dark if the player needs a light to see everything; bright if empty
+}
+
+// a way out of a room. Exits without a passage are always open and can only
+// be reached by the name of the room they lead to.
+type Exit struct {
+	To        string
+	Direction string     // north, south, east, west, up, down, in or out
+	Aliases   []string   // other names the player may use for the exit
+	Via       string     // a feature the player goes through, like a laundry chute; such exits are one-way and aren't listed in the room's Exits
+	If        *Condition // what the player needs to get through
+	Climbed   bool       // the player must have climbed up to reach the exit
+	Block     string     // why the player cannot get through
+	First     string     // narration the first time the player goes to 'To'
+	Travel    []*Line    // narration as the player goes; every line whose condition holds is used
+	Carrier   string     // an NPC who takes the player through
+	Arrives   string     // how other players see someone arrive; %s is their name
+	Effort    int        // how much energy it takes to get through
 }
 
 // struct used for both features and objects
 type Item struct {
 	Name                 string
 	Description          string
-	TooBig               bool
+	Size                 int // how big the item is before it's shrunk
+	Shrunk               int // how many sizes smaller the shrink ray has made it
+	Weight               int // how heavy the item is before it's shrunk
 	IsFeature            bool
 	Discovered           bool
 	ContainsHiddenObject bool
 	DiscoveryStatement   string
 	HiddenObject         string
 	IsEdible             bool
+	Nutrition            int    // how much energy eating it gives back
+	Eaten                string // what the player is told as they eat it
+	IsLight              bool   // it gives light when it's lit
+	Lit                  bool
+	Burns                int  // how many more turns it will burn for once it's lit
+	Everlasting          bool // it's a light which never runs out or needs a flame
+	Flame                bool // other items can be lit from it, like a fire
+	IsContainer          bool
+	Openable             bool             // the container can be opened and closed
+	Closed               bool             // the container's contents can't be seen or reached
+	Locked               bool             // the container can't be opened without its key
+	Key                  string           // the item which locks and unlocks the container
+	Contents             map[string]*Item // what is inside the container
+	Capacity             int              // how much more weight the player can carry with the item, like a backpack
+	Parts                []string         // the items which went into making it
 }
 
 // struct to store game state
 type Game struct {
-	CurRoom       string
-	Rooms         map[string]*Room
-	Inventory     map[string]*Item
-	ClimbedUp     bool
-	EagleWatching bool
+	CurRoom      string
+	Rooms        map[string]*Room
+	Inventory    map[string]*Item
+	ClimbedUp    bool
+	Shrunk       int
+	Tired        int
+	Confirming   string
+	NPCs         map[string]*NPC
+	Encounters   map[string]*Encounter
+	Flags        map[string]bool
+	Penalty      int
+	Conversation *Conversation
+	Turns        int
+	LastMessage  string
+	Verbosity    int
+}
+
+// the directions an exit may lead in, and their abbreviations
+var directions = map[string]string{
+	"north": "north", "n": "north",
+	"south": "south", "s": "south",
+	"east": "east", "e": "east",
+	"west": "west", "w": "west",
+	"up": "up", "u": "up",
+	"down": "down", "d": "down",
+	"in":  "in",
+	"out": "out",
 }
 
 // items needed to win the game
@@ -67,58 +136,332 @@ var winningItems = []string{"shampoo", "dirty socks", "aluminum can", "couch stu
 // corresponding Room struct. Rooms must be defined as JSON and saved to the
 // 'rooms' directory relative to the game's home directory.
 func loadRooms() {
-	files, e := ioutil.ReadDir("rooms")
+	m, _, e := readRooms("rooms")
 	if e != nil {
 		log.Fatal(e)
 	}
 
+	if problems := checkRooms(m); len(problems) > 0 {
+		panic(problems[0].Error())
+	}
+
+	for name, r := range m {
+		rooms[name] = r
+		if r.Alias != "" {
+			roomAliases[r.Alias] = r.Name
+		}
+	}
+
+	if e := localizeRooms(rooms); e != nil {
+		log.Fatal(e)
+	}
+
+	world = copyRooms(rooms)
+	for _, r := range world {
+		addToCatalog(r.Items)
+	}
+}
+
+// readRooms reads the room definitions in the directory 'dir'. It returns
+// them by name, along with the file each one came from.
+func readRooms(dir string) (map[string]*Room, map[string]string, error) {
+	files, e := ioutil.ReadDir(dir)
+	if e != nil {
+		return nil, nil, e
+	}
+
+	m := make(map[string]*Room)
+	from := make(map[string]string)
 	for _, f := range files {
 		matched, _ := regexp.MatchString(`\.json`, f.Name())
 		if matched == true {
-			roomJson, e := ioutil.ReadFile("rooms/" + f.Name())
-
+			roomJson, e := ioutil.ReadFile(filepath.Join(dir, f.Name()))
 			if e != nil {
-				log.Fatal(e)
+				return nil, nil, e
 			}
 
 			var r Room
-			json.Unmarshal([]byte(roomJson), &r)
-			rooms[r.Name] = &r
+			if e := json.Unmarshal([]byte(roomJson), &r); e != nil {
+				return nil, nil, fmt.Errorf("%s: %v", f.Name(), e)
+			}
+			m[r.Name] = &r
+			from[r.Name] = f.Name()
 		}
 	}
 
-	for _, r := range rooms {
-		if r.Alias != "" {
-			roomAliases[r.Alias] = r.Name
+	return m, from, nil
+}
+
+// checkRooms returns everything wrong with the room definitions 'm', like
+// passages to rooms that don't exist
+func checkRooms(m map[string]*Room) []error {
+	var problems []error
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sum := 0
+	for _, name := range names {
+		r := m[name]
+		if r == nil {
+			problems = append(problems, fmt.Errorf("%s is a room with nothing in it", name))
+			continue
 		}
+		if r.Items == nil {
+			problems = append(problems, fmt.Errorf("%s has no list of items", r.Name))
+		}
+		sum += len(r.Items)
+
+		for _, e := range r.Exits {
+			if _, ok := m[e]; !ok {
+				problems = append(problems, fmt.Errorf("%s has an exit to a room that doesn't exist: %s", r.Name, e))
+			}
+		}
+
+		for _, p := range r.Passages {
+			if p == nil {
+				problems = append(problems, fmt.Errorf("%s has a passage with nothing in it", r.Name))
+				continue
+			}
+
+			for _, l := range p.Travel {
+				if l == nil {
+					problems = append(problems, fmt.Errorf("%s has a passage to %s with an empty line of travel", r.Name, p.To))
+				}
+			}
+
+			if _, ok := m[p.To]; !ok {
+				problems = append(problems, fmt.Errorf("%s has a passage to a room that doesn't exist: %s", r.Name, p.To))
+			}
+
+			if d, ok := directions[p.Direction]; p.Direction != "" && (!ok || d != p.Direction) {
+				problems = append(problems, fmt.Errorf("%s has a passage in a direction that doesn't exist: %s", r.Name, p.Direction))
+			}
+		}
+
+		problems = append(problems, checkItems(r.Name, r.Items, r.Items)...)
+	}
+
+	if len(m) < MinRooms {
+		problems = append(problems, fmt.Errorf("The game must have at least %d rooms", MinRooms))
+	}
+
+	if sum < MinItems {
+		problems = append(problems, fmt.Errorf("The game must have at least %d items", MinItems))
+	}
+
+	return problems
+}
+
+// checkItems returns everything wrong with 'items' in the room called 'room',
+// whose top-level items are 'all'
+func checkItems(room string, items map[string]*Item, all map[string]*Item) []error {
+	var problems []error
+	var names []string
+	for name := range items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		item := items[name]
+		if item == nil {
+			problems = append(problems, fmt.Errorf("%s has an item with nothing in it: %s", room, name))
+			continue
+		}
+
+		if item.Name != name {
+			problems = append(problems, fmt.Errorf("%s has an item called %s listed as %s", room, item.Name, name))
+		}
+
+		if item.Size < Tiny || item.Size > Huge {
+			problems = append(problems, fmt.Errorf("%s has a size that doesn't exist: %d", name, item.Size))
+		}
+
+		if item.ContainsHiddenObject {
+			if _, ok := all[item.HiddenObject]; !ok {
+				problems = append(problems, fmt.Errorf("%s in %s hides something that isn't in the room: %s", name, room, item.HiddenObject))
+			}
+		}
+
+		problems = append(problems, checkItems(room, item.Contents, all)...)
+	}
+
+	return problems
+}
+
+// addToCatalog lists 'items', and anything inside them, in the catalog
+func addToCatalog(items map[string]*Item) {
+	for name, item := range items {
+		if item.Size < Tiny || item.Size > Huge {
+			panic(fmt.Sprintf("%s has a size that doesn't exist: %d", name, item.Size))
+		}
+
+		catalog[name] = item
+		addToCatalog(item.Contents)
+	}
+}
+
+// copyRooms returns a deep copy of the rooms in 'm', so that a new game can
+// be started without disturbing any game already in progress.
+func copyRooms(m map[string]*Room) map[string]*Room {
+	b, e := json.Marshal(m)
+	if e != nil {
+		log.Fatal(e)
+	}
+
+	c := make(map[string]*Room)
+	json.Unmarshal(b, &c)
+
+	return c
+}
+
+// newGame returns the state of a game that has just begun. The player always
+// starts in the attic with the shrink ray.
+func newGame() Game {
+	s := Item{
+		Name:        "shrink ray",
+		Description: "Your parents' latest invention.",
+		Size:        Small,
+		Weight:      2,
+		IsFeature:   false,
+		Discovered:  true,
+		IsEdible:    false,
+		IsContainer: true,
 	}
 
-	// Panic if fewer than 15 rooms are defined.
-	if len(rooms) < MinRooms {
-		panic("The game must have at least 15 rooms")
+	b := newBackpack()
+
+	return Game{
+		CurRoom:    "Attic",
+		Rooms:      copyRooms(world),
+		Inventory:  map[string]*Item{s.Name: &s, b.Name: b},
+		NPCs:       copyNPCs(cast),
+		Encounters: copyEncounters(perils),
 	}
+}
 
-	//  Panic if fewer than 8 items are defined.
-	var sum int
+// currentGame captures the state of the game in progress
+func currentGame() Game {
+	return Game{
+		CurRoom:      curRoom.Name,
+		Rooms:        rooms,
+		Inventory:    inventory,
+		ClimbedUp:    climbedUp,
+		Shrunk:       shrunk,
+		Tired:        tired,
+		Confirming:   confirming,
+		NPCs:         npcs,
+		Encounters:   encounters,
+		Flags:        flags,
+		Penalty:      penalty,
+		Conversation: talking,
+		Turns:        turns,
+		LastMessage:  lastMessage,
+		Verbosity:    verbosity,
+	}
+}
+
+// restoreGame replaces the game in progress with the state in 'g'
+func restoreGame(g Game) {
+	// in the interest of catching errors early, wipe the current game data
+	rooms = nil
+	rooms = g.Rooms
 
+	inventory = nil
+	inventory = g.Inventory
+
+	curRoom = nil
+	curRoom = rooms[g.CurRoom] // must be set after loading rooms!
+
+	// games saved before passages were described, or before rooms had a
+	// place on the map, get them from the rooms as they were loaded
+	for name, r := range rooms {
+		if w, ok := world[name]; ok && r.Passages == nil {
+			r.Passages = w.Passages
+		}
+		if w, ok := world[name]; ok && r.Layout == nil {
+			r.Layout = w.Layout
+		}
+	}
+
+	// games saved before items had sizes get them from the rooms as they
+	// were loaded
 	for _, r := range rooms {
-		sum += len(r.Items)
+		resize(r.Items)
 	}
+	resize(inventory)
 
-	if sum < MinItems {
-		panic("The game must have at least 8 items")
+	// games saved before there was a backpack find it on their back
+	if !anywhere(inventory, "backpack") {
+		lost := false
+		for _, r := range rooms {
+			lost = lost || anywhere(r.Items, "backpack")
+		}
+
+		if !lost {
+			b := newBackpack()
+			inventory[b.Name] = b
+		}
+	}
+
+	// games saved before the shrink ray could be repaired can put parts in it
+	if s, ok := inventory["shrink ray"]; ok {
+		s.IsContainer = true
+	}
+
+	climbedUp = g.ClimbedUp
+	shrunk = g.Shrunk
+	tired = g.Tired
+	confirming = g.Confirming
+
+	// games saved before there were NPCs or encounters start with everyone
+	// where they began
+	npcs = nil
+	npcs = g.NPCs
+	if npcs == nil {
+		npcs = copyNPCs(cast)
+	}
+
+	encounters = nil
+	encounters = g.Encounters
+	if encounters == nil {
+		encounters = copyEncounters(perils)
+	}
+
+	flags = g.Flags
+	if flags == nil {
+		flags = make(map[string]bool)
+	}
+
+	penalty = g.Penalty
+	talking = g.Conversation
+	turns = g.Turns
+	lastMessage = g.LastMessage
+	verbosity = g.Verbosity
+
+	exitsChanged()
+	inventoryChanged()
+}
+
+// resize gives any of 'items' without a size the size they were loaded with
+func resize(items map[string]*Item) {
+	for name, item := range items {
+		if item.Size == 0 {
+			if w, ok := catalog[name]; ok {
+				item.Size = w.Size
+				item.Weight = w.Weight
+			}
+		}
+		resize(item.Contents)
 	}
 }
 
 // saveGame dumps the current game state to a timestamped JSON file
 func saveGame() {
-	g := Game{
-		CurRoom:       curRoom.Name,
-		Rooms:         rooms,
-		Inventory:     inventory,
-		ClimbedUp:     climbedUp,
-		EagleWatching: eagleWatching,
-	}
+	g := currentGame()
 
 	b, e := json.MarshalIndent(g, "", " ")
 	if e != nil {
@@ -130,7 +473,7 @@ func saveGame() {
 	f = strings.ToLower(f)
 	_ = ioutil.WriteFile(f, b, 0644)
 
-	fmt.Printf("Saved game %s\n", f)
+	say("saved-game", "Saved game %s", f)
 }
 
 // loadGame loads a saved game from the file 's'
@@ -138,18 +481,16 @@ func loadGame(s string) {
 	gameJson, e := ioutil.ReadFile(s)
 
 	if e != nil {
-		fmt.Printf("File '%s' not found!\n", s)
+		say("file-not-found", "File '%s' not found!", s)
 		return
 	}
 
 	// player must confirm they want to load a saved game
-	fmt.Printf("Load game '%s'. Are you sure? ('y' or 'n')\n", s)
-	input := bufio.NewScanner(os.Stdin)
+	say("load-game-are-you", "Load game '%s'. Are you sure? ('y' or 'n')", s)
 
 Goto:
-	for input.Scan() {
-		action := input.Text()
-		s := strings.Fields(action)
+	for in.Scan() {
+		s := strings.Fields(translateAnswer(in.Text()))
 
 		if cap(s) == 0 {
 			continue
@@ -161,7 +502,7 @@ Goto:
 		case "n":
 			return
 		default:
-			fmt.Println("Please type 'y' or 'n'.")
+			say("please-type-y-or", "Please type 'y' or 'n'.")
 		}
 	}
 
@@ -169,37 +510,71 @@ Goto:
 	var g Game
 	json.Unmarshal([]byte(gameJson), &g)
 
-	// in the interest of catching errors early, wipe the current game data
-	rooms = nil
-	rooms = g.Rooms
-
-	inventory = nil
-	inventory = g.Inventory
+	restoreGame(g)
+}
 
-	curRoom = nil
-	curRoom = rooms[g.CurRoom] // must be set after loading rooms!
+func main() {
+	addr := flag.String("http", "", "serve the game as a JSON API on this address instead of the terminal")
+	ttl := flag.Duration("session-ttl", 30*time.Minute, "evict API sessions idle for longer than this")
+	dir := flag.String("session-dir", "", "persist API sessions to this directory")
+	sshAddr := flag.String("ssh", "", "serve the game over SSH on this address instead of the terminal")
+	sshDir := flag.String("ssh-dir", "ssh", "keep the SSH host key and player saves in this directory")
+	shared := flag.Bool("shared", false, "SSH players share one house instead of playing alone")
+	protocol := flag.String("protocol", "", "play with a program instead of a person; the only protocol is 'jsonl'")
+	lang := flag.String("lang", "", "play in this language, like 'es'; the default is from LANG")
+	fullScreen := flag.Bool("tui", false, "play in a full-screen terminal interface instead of line by line")
+	dev := flag.Bool("dev", false, "reload the rooms as their files change, while writing them")
+	flag.BoolVar(&accessible, "accessible", false, "play with a screen reader: no pictures, and lists and exits read as sentences")
+	if len(os.Args) > 1 && os.Args[1] == "map" {
+		mapCommand(os.Args[2:])
+		return
+	}
 
-	climbedUp = g.ClimbedUp
+	if len(os.Args) > 1 && os.Args[1] == "edit" {
+		editCommand(os.Args[2:])
+		return
+	}
 
-	eagleWatching = g.EagleWatching
-}
+	flag.Parse()
 
-func main() {
+	loadLocale(pickLanguage(*lang))
 	loadRooms()
+	loadRecipes()
+	loadNPCs()
+	loadEncounters()
+	loadDialogues()
+
+	switch *protocol {
+	case "":
+	case "jsonl":
+		playProtocol(os.Stdin, os.Stdout)
+		return
+	default:
+		log.Fatalf("unknown protocol '%s'", *protocol)
+	}
 
-	curRoom = rooms["Attic"]
+	if *addr != "" {
+		serveAPI(*addr, *ttl, *dir)
+		return
+	}
 
-	// the player always starts with the shrink ray
-	s := Item{
-		Name:        "shrink ray",
-		Description: "Your parents' latest invention.",
-		TooBig:      false,
-		IsFeature:   false,
-		Discovered:  true,
-		IsEdible:    false,
+	if *sshAddr != "" {
+		serveSSH(*sshAddr, *sshDir, *shared)
+		return
 	}
 
-	inventory[s.Name] = &s
+	restoreGame(newGame())
+	out = &styler{os.Stdout, terminalStyle()}
+	devMode = *dev
+
+	if term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd())) {
+		in = bufio.NewScanner(newLineReader())
+	}
+
+	if *fullScreen && !accessible {
+		playTUI()
+		return
+	}
 
 	playGame()
 }
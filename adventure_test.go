@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// TestMain loads the house, the way main does, before any test plays in it
+func TestMain(m *testing.M) {
+	loadLocale("")
+	loadRooms()
+	loadRecipes()
+	loadNPCs()
+	loadEncounters()
+	loadDialogues()
+	out = ioutil.Discard
+
+	os.Exit(m.Run())
+}
+
+// playIn starts a new game with the player in 'room', plays 'commands' and
+// returns what the game said
+func playIn(room string, commands ...string) string {
+	g := newGame()
+	g.CurRoom = room
+	restoreGame(g)
+
+	var b bytes.Buffer
+	out = &b
+	defer func() { out = ioutil.Discard }()
+
+	for _, c := range commands {
+		parseCommand(c)
+	}
+
+	return b.String()
+}
+
+func TestCheckRoomsReportsWhatsMissing(t *testing.T) {
+	m, _, e := readRooms("rooms")
+	if e != nil {
+		t.Fatal(e)
+	}
+	if problems := checkRooms(m); len(problems) > 0 {
+		t.Fatalf("the house has problems: %v", problems)
+	}
+
+	m["Kitchen"].Passages = append(m["Kitchen"].Passages, nil)
+	m["Pantry"].Items["shelves"] = nil
+	m["Attic"].Items = nil
+
+	want := []string{
+		"Attic has no list of items",
+		"Kitchen has a passage with nothing in it",
+		"Pantry has an item with nothing in it: shelves",
+	}
+	problems := checkRooms(m)
+	for _, w := range want {
+		found := false
+		for _, p := range problems {
+			found = found || p.Error() == w
+		}
+		if !found {
+			t.Errorf("checkRooms didn't say %q, only %v", w, problems)
+		}
+	}
+}
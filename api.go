@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// engineMu guards the game globals while a session takes its turn. The
+// game engine only knows how to play one game at a time, so each session's
+// state is swapped in, played, and swapped back out again.
+var engineMu sync.Mutex
+
+// a game being played through the JSON API
+type Session struct {
+	ID       string
+	Game     Game
+	GameOver bool
+	LastUsed time.Time
+	dice     *rand.Rand // chance for this session alone; the shared dice if nil
+}
+
+// the result of a command submitted to a session
+type TurnResult struct {
+	Output    []string `json:"output,omitempty"`
+	Room      string   `json:"room"`
+	Inventory []string `json:"inventory"`
+	Score     int      `json:"score"`
+	GameOver  bool     `json:"gameOver"`
+}
+
+// all the sessions known to the API server
+type SessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+	ttl      time.Duration
+	dir      string // if not empty, sessions are persisted here
+}
+
+// newSessionStore creates a session store, loading any sessions previously
+// persisted to 'dir'.
+func newSessionStore(ttl time.Duration, dir string) *SessionStore {
+	st := &SessionStore{
+		sessions: make(map[string]*Session),
+		ttl:      ttl,
+		dir:      dir,
+	}
+
+	if dir == "" {
+		return st
+	}
+
+	if e := os.MkdirAll(dir, 0755); e != nil {
+		log.Fatal(e)
+	}
+
+	files, e := ioutil.ReadDir(dir)
+	if e != nil {
+		log.Fatal(e)
+	}
+
+	for _, f := range files {
+		if filepath.Ext(f.Name()) != ".json" {
+			continue
+		}
+
+		b, e := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if e != nil {
+			log.Print(e)
+			continue
+		}
+
+		var s Session
+		if e := json.Unmarshal(b, &s); e != nil {
+			log.Printf("skipping unreadable session %s", f.Name())
+			continue
+		}
+		if e := checkGame(s.Game); e != nil {
+			log.Printf("skipping unreadable session %s: %v", f.Name(), e)
+			continue
+		}
+		st.sessions[s.ID] = &s
+	}
+
+	return st
+}
+
+// checkGame returns what's wrong with 'g', if it isn't complete enough to be
+// played. Its rooms and inventory must pass the checks the rooms are loaded
+// with.
+func checkGame(g Game) error {
+	if g.Rooms == nil || g.Inventory == nil {
+		return errors.New("the game has no rooms or no inventory")
+	}
+
+	if r, ok := g.Rooms[g.CurRoom]; !ok || r == nil {
+		return fmt.Errorf("the player is in a room that doesn't exist: %s", g.CurRoom)
+	}
+
+	problems := checkRooms(g.Rooms)
+	problems = append(problems, checkItems("the inventory", g.Inventory, g.Inventory)...)
+	if len(problems) > 0 {
+		return problems[0]
+	}
+
+	return nil
+}
+
+// add stores a new session holding the game 'g'
+func (st *SessionStore) add(g Game) *Session {
+	b := make([]byte, 16)
+	if _, e := crand.Read(b); e != nil {
+		log.Fatal(e)
+	}
+
+	s := &Session{
+		ID:       hex.EncodeToString(b),
+		Game:     g,
+		LastUsed: time.Now(),
+	}
+
+	st.mu.Lock()
+	st.sessions[s.ID] = s
+	st.mu.Unlock()
+
+	return s
+}
+
+// get finds the session 'id', marking it as recently used
+func (st *SessionStore) get(id string) (*Session, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	s, ok := st.sessions[id]
+	if ok {
+		s.LastUsed = time.Now()
+	}
+
+	return s, ok
+}
+
+// remove forgets the session 'id', including any copy on disk
+func (st *SessionStore) remove(id string) {
+	st.mu.Lock()
+	delete(st.sessions, id)
+	st.mu.Unlock()
+
+	if st.dir != "" {
+		os.Remove(filepath.Join(st.dir, id+".json"))
+	}
+}
+
+// persist writes session 's' to disk, if sessions are being persisted.
+// Callers must hold engineMu.
+func (st *SessionStore) persist(s *Session) {
+	if st.dir == "" {
+		return
+	}
+
+	b, e := json.Marshal(s)
+	if e != nil {
+		log.Print(e)
+		return
+	}
+
+	if e := ioutil.WriteFile(filepath.Join(st.dir, s.ID+".json"), b, 0644); e != nil {
+		log.Print(e)
+	}
+}
+
+// evict periodically removes sessions which have been idle longer than the
+// store's TTL. It never returns.
+func (st *SessionStore) evict() {
+	for range time.Tick(st.ttl / 2) {
+		var idle []string
+
+		st.mu.Lock()
+		for id, s := range st.sessions {
+			if time.Since(s.LastUsed) > st.ttl {
+				idle = append(idle, id)
+			}
+		}
+		st.mu.Unlock()
+
+		for _, id := range idle {
+			st.remove(id)
+		}
+	}
+}
+
+// playTurn runs the command 'action' against session 's' and reports what
+// happened. Only one session may take a turn at a time. Commands which prompt
+// the player on the terminal must be handled by the caller.
+func playTurn(s *Session, action string) TurnResult {
+	engineMu.Lock()
+	defer engineMu.Unlock()
+
+	restoreGame(s.Game)
+	gameOver = s.GameOver
+
+	if s.dice != nil {
+		shared := dice
+		dice = s.dice
+		defer func() { dice = shared }()
+	}
+
+	var buf bytes.Buffer
+	prev := out
+	out = &buf
+	defer func() { out = prev }()
+
+	if gameOver {
+		buf.WriteString("The game is over. Start a new session to play again.\n")
+	} else {
+		parseCommand(action)
+		if gameOver {
+			endGame()
+		}
+	}
+
+	s.Game = currentGame()
+	s.GameOver = gameOver
+
+	r := turnState(s)
+	r.Output = strings.Split(strings.Trim(buf.String(), "\n"), "\n")
+
+	return r
+}
+
+// terminalCommand reports whether 'action' is one of the commands which
+// prompts the player on the terminal
+func terminalCommand(action string) bool {
+	f := strings.Fields(strings.ToLower(action))
+	return len(f) > 0 && (f[0] == "savegame" || f[0] == "loadgame" || f[0] == "quit")
+}
+
+// turnState summarizes session 's' without running any command
+func turnState(s *Session) TurnResult {
+	r := TurnResult{
+		Room:     s.Game.CurRoom,
+		Score:    score(s.Game.Inventory) - s.Game.Penalty,
+		GameOver: s.GameOver,
+	}
+
+	for name := range s.Game.Inventory {
+		r.Inventory = append(r.Inventory, name)
+	}
+	sort.Strings(r.Inventory)
+
+	return r
+}
+
+// writeJSON sends 'v' as the JSON body of a response
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError sends an error message as the JSON body of a response
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
+
+// handleSessions routes requests for the API:
+//
+//	POST   /sessions               start a new game
+//	POST   /sessions/import        start a game from an exported save
+//	GET    /sessions/{id}/state    summarize a game
+//	POST   /sessions/{id}/commands submit a command, {"command": "look"}
+//	GET    /sessions/{id}/save     export a game as a save
+//	DELETE /sessions/{id}          end a game
+func (st *SessionStore) handleSessions(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/sessions"), "/")
+	parts := strings.Split(path, "/")
+
+	switch {
+	case path == "" && r.Method == http.MethodPost:
+		s := st.add(newGame())
+		st.respond(w, http.StatusCreated, s, TurnResult{Output: renderLines(strings.Split(strings.Trim(text("opening", openingMessage), "\n"), "\n"), outputStyle(r))})
+	case path == "import" && r.Method == http.MethodPost:
+		var g Game
+		if e := json.NewDecoder(r.Body).Decode(&g); e != nil {
+			writeError(w, http.StatusBadRequest, "not a valid saved game")
+			return
+		}
+		if e := checkGame(g); e != nil {
+			writeError(w, http.StatusBadRequest, "not a valid saved game: "+e.Error())
+			return
+		}
+		s := st.add(g)
+		st.respond(w, http.StatusCreated, s, TurnResult{})
+	case len(parts) == 1 && path != "" && r.Method == http.MethodDelete:
+		if _, ok := st.get(parts[0]); !ok {
+			writeError(w, http.StatusNotFound, "no such session")
+			return
+		}
+		st.remove(parts[0])
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 2:
+		s, ok := st.get(parts[0])
+		if !ok {
+			writeError(w, http.StatusNotFound, "no such session")
+			return
+		}
+		st.handleSession(w, r, s, parts[1])
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// handleSession serves the requests addressed to a single session
+func (st *SessionStore) handleSession(w http.ResponseWriter, r *http.Request, s *Session, what string) {
+	switch {
+	case what == "commands" && r.Method == http.MethodPost:
+		var c struct {
+			Command string `json:"command"`
+		}
+		if e := json.NewDecoder(r.Body).Decode(&c); e != nil {
+			writeError(w, http.StatusBadRequest, "expected {\"command\": \"...\"}")
+			return
+		}
+		if terminalCommand(c.Command) {
+			// these prompt on the terminal, which API players don't have
+			engineMu.Lock()
+			res := turnState(s)
+			engineMu.Unlock()
+
+			res.Output = []string{"Use the API to export or import saved games."}
+			writeJSON(w, http.StatusOK, res)
+			return
+		}
+		res := playTurn(s, c.Command)
+		res.Output = renderLines(res.Output, outputStyle(r))
+
+		engineMu.Lock()
+		st.persist(s)
+		engineMu.Unlock()
+
+		writeJSON(w, http.StatusOK, res)
+	case what == "state" && r.Method == http.MethodGet:
+		engineMu.Lock()
+		res := turnState(s)
+		engineMu.Unlock()
+
+		writeJSON(w, http.StatusOK, res)
+	case what == "save" && r.Method == http.MethodGet:
+		engineMu.Lock()
+		defer engineMu.Unlock()
+
+		writeJSON(w, http.StatusOK, s.Game)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// outputStyle is how the game's text is shown to the client: as plain text,
+// or as HTML links with ?markup=html
+func outputStyle(r *http.Request) int {
+	if r.URL.Query().Get("markup") == "html" {
+		return HTMLText
+	}
+
+	return PlainText
+}
+
+// respond persists a newly created session and reports it to the client
+func (st *SessionStore) respond(w http.ResponseWriter, code int, s *Session, r TurnResult) {
+	engineMu.Lock()
+	st.persist(s)
+	state := turnState(s)
+	engineMu.Unlock()
+
+	state.Output = r.Output
+	writeJSON(w, code, struct {
+		ID string `json:"id"`
+		TurnResult
+	}{s.ID, state})
+}
+
+// serveAPI plays the game over HTTP on 'addr' until the server fails
+func serveAPI(addr string, ttl time.Duration, dir string) {
+	st := newSessionStore(ttl, dir)
+	go st.evict()
+
+	http.HandleFunc("/sessions", st.handleSessions)
+	http.HandleFunc("/sessions/", st.handleSessions)
+
+	log.Printf("serving the game on %s", addr)
+	log.Fatal(http.ListenAndServe(addr, nil))
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestImportChecksTheGame(t *testing.T) {
+	st := newSessionStore(0, "")
+	saved, e := json.Marshal(newGame())
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	for _, c := range []struct {
+		about  string
+		change func(g *Game)
+		want   int
+	}{
+		{"a new game", func(g *Game) {}, http.StatusCreated},
+		{"no rooms", func(g *Game) { g.Rooms = nil }, http.StatusBadRequest},
+		{"a room with nothing in it", func(g *Game) { g.Rooms["Kitchen"] = nil }, http.StatusBadRequest},
+		{"a room with no items", func(g *Game) { g.Rooms["Kitchen"].Items = nil }, http.StatusBadRequest},
+		{"the player nowhere", func(g *Game) { g.CurRoom = "Garage" }, http.StatusBadRequest},
+		{"an empty item carried", func(g *Game) { g.Inventory["rock"] = nil }, http.StatusBadRequest},
+		{"a passage to nowhere", func(g *Game) {
+			g.Rooms["Kitchen"].Passages = append(g.Rooms["Kitchen"].Passages, &Exit{To: "Garage"})
+		}, http.StatusBadRequest},
+	} {
+		var g Game
+		if e := json.Unmarshal(saved, &g); e != nil {
+			t.Fatal(e)
+		}
+		c.change(&g)
+		b, e := json.Marshal(g)
+		if e != nil {
+			t.Fatal(e)
+		}
+
+		w := httptest.NewRecorder()
+		st.handleSessions(w, httptest.NewRequest(http.MethodPost, "/sessions/import", bytes.NewReader(b)))
+		if w.Code != c.want {
+			t.Errorf("importing %s answered %d, want %d: %s", c.about, w.Code, c.want, w.Body)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"sort"
+)
+
+// how much weight the player can carry without a backpack
+const handsCapacity = 4
+
+// newBackpack returns the backpack every player starts with
+func newBackpack() *Item {
+	return &Item{
+		Name:        "backpack",
+		Description: "Your trusty backpack. It shrank right along with you.\nEverything you pick up goes in here.",
+		Size:        Small,
+		Weight:      1,
+		Capacity:    24,
+		Discovered:  true,
+	}
+}
+
+// carrying is the total weight of the items in 'inv', and everything inside
+// them
+func carrying(inv map[string]*Item) int {
+	w := 0
+	for _, item := range inv {
+		w += item.weight() + carrying(item.Contents)
+	}
+
+	return w
+}
+
+// capacity is how much weight the player carrying 'inv' can manage
+func capacity(inv map[string]*Item) int {
+	c := handsCapacity
+	for _, item := range inv {
+		c += item.Capacity
+	}
+
+	return c
+}
+
+// canCarry reports whether the player carrying 'inv' can pick up 'item' too.
+// If not, it says why.
+func canCarry(inv map[string]*Item, item *Item) bool {
+	spare := capacity(inv) - carrying(inv)
+	w := item.weight() + carrying(item.Contents)
+	if w <= spare {
+		return true
+	}
+
+	if spare <= 0 {
+		say("your-backpack-is-full", "Your backpack is full. You'll have to {verb:drop} something before you can take the %s.", item.Name)
+	} else {
+		say("the-is-too-heavy", "The %s is too heavy. It weighs %d and you can only carry %d more.", item.Name, w, spare)
+	}
+
+	if item.size() > Microscopic && !item.IsFeature {
+		say("maybe-if-you-shrink", "Maybe if you {verb:shrink} it, it'll be lighter.")
+	}
+
+	return false
+}
+
+// carried reports whether 'item' is somewhere in the player's inventory
+func carried(item *Item) bool {
+	for _, val := range inventory {
+		if val == item || holds(val, item) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// anywhere reports whether an item called 'name' is among 'items' or inside
+// any of them, open or not
+func anywhere(items map[string]*Item, name string) bool {
+	for n, item := range items {
+		if n == name || anywhere(item.Contents, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listInventory lists the contents of your inventory and how full your
+// backpack is.
+func listInventory() {
+	var names []string
+	for key := range inventory {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+
+	emit(Event{Kind: InventoryListed, Items: names, Load: carrying(inventory), Capacity: capacity(inventory)})
+}
+
+// canLetGo reports whether the player could carry everything else without
+// 'val', telling them to drop something else first if they couldn't
+func canLetGo(val *Item) bool {
+	if val.Capacity > 0 && carrying(inventory)-val.weight() > capacity(inventory)-val.Capacity {
+		say("you-cant-carry-everything", "You can't carry everything without your %s. {verb:drop} something else first.", val.Name)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestCanCarry(t *testing.T) {
+	inv := map[string]*Item{"backpack": {Name: "backpack", Weight: 1, Capacity: 24}}
+	spare := handsCapacity + 24 - 1
+
+	for _, c := range []struct {
+		item *Item
+		want bool
+	}{
+		{&Item{Name: "rock", Weight: spare}, true},
+		{&Item{Name: "rock", Weight: spare + 1}, false},
+		{&Item{Name: "rock", Weight: 2 * (spare + 1), Shrunk: 1}, false},
+		{&Item{Name: "rock", Weight: 2 * spare, Shrunk: 1}, true},
+		{&Item{Name: "box", Weight: 1, Contents: map[string]*Item{"rock": {Name: "rock", Weight: spare}}}, false},
+	} {
+		if got := canCarry(inv, c.item); got != c.want {
+			t.Errorf("canCarry(%+v) = %v, want %v", c.item, got, c.want)
+		}
+	}
+}
+
+func TestBackpackStaysWhenEverythingElseIsTooHeavy(t *testing.T) {
+	for _, command := range []string{"drop backpack", "put backpack in refrigerator"} {
+		playIn("Kitchen")
+		inventory["rock"] = &Item{Name: "rock", Weight: handsCapacity + 1, Discovered: true}
+		curRoom.Items["refrigerator"].Closed = false
+
+		parseCommand(command)
+		if _, ok := inventory["backpack"]; !ok {
+			t.Errorf("%q left the player carrying more than they can", command)
+		}
+	}
+
+	playIn("Kitchen")
+	parseCommand("drop backpack")
+	if _, ok := inventory["backpack"]; ok {
+		t.Error("the player couldn't drop the backpack with their hands free")
+	}
+}
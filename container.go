@@ -0,0 +1,283 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// findItem looks for 'name' among 'items' and inside any open containers
+// among them. It returns the map holding the item, so that the item can be
+// taken out of it.
+func findItem(items map[string]*Item, name string) (map[string]*Item, bool) {
+	if _, ok := items[name]; ok {
+		return items, true
+	}
+
+	for _, val := range items {
+		if val.IsContainer && !val.Closed {
+			if where, ok := findItem(val.Contents, name); ok {
+				return where, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// findContainer looks for the container 'name' in the current room or the
+// player's inventory. Containers in the room have to be visible.
+func findContainer(name string) (*Item, bool) {
+	where, ok := findItem(inventory, name)
+	held := ok
+	if !ok {
+		where, ok = findItem(curRoom.Items, name)
+	}
+
+	if !ok || !where[name].Discovered || !held && !visible(where[name]) {
+		say("not-found", "%s not found.", name)
+		return nil, false
+	}
+
+	if where[name].Capacity > 0 && carried(where[name]) {
+		say("everything-you-carry-is", "Everything you carry is already in your %s.", name)
+		return nil, false
+	}
+
+	if !where[name].IsContainer {
+		say("the-cant-hold-anything", "The %s can't hold anything.", name)
+		return nil, false
+	}
+
+	return where[name], true
+}
+
+// listContents prints what is inside the container 'val'
+func listContents(val *Item) {
+	if val.Closed {
+		say("the-is-closed", "The %s is closed.", val.Name)
+		return
+	}
+
+	var names []string
+	for name := range val.Contents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		say("the-is-empty", "The %s is empty.", val.Name)
+		return
+	}
+
+	if accessible {
+		sayContents(val)
+		return
+	}
+
+	say("inside-the-you-see", "Inside the %s you see:", val.Name)
+	for _, name := range names {
+		narrate("     " + localName(name))
+	}
+}
+
+// holds reports whether 'inner' is somewhere inside the container 'outer'
+func holds(outer *Item, inner *Item) bool {
+	for _, val := range outer.Contents {
+		if val == inner || holds(val, inner) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lookInside prints what is inside the container 'name', if there's light
+// enough to see it
+func lookInside(name string) {
+	if curRoom.Light == Dark && !lit() {
+		say("its-too-dark-to", "It's too dark to make that out.")
+		return
+	}
+
+	if val, ok := findContainer(name); ok {
+		listContents(val)
+	}
+}
+
+// openContainer opens the container 'name' and shows what is inside
+func openContainer(name string) {
+	val, ok := findContainer(name)
+	if !ok {
+		return
+	}
+
+	if !val.Openable {
+		say("the-doesnt-open", "The %s doesn't open.", name)
+		return
+	}
+
+	if !val.Closed {
+		say("the-is-already-open", "The %s is already open.", name)
+		return
+	}
+
+	if val.Locked {
+		say("the-is-locked", "The %s is locked.", name)
+		return
+	}
+
+	val.Closed = false
+	say("you-open-the", "You open the %s.", name)
+	listContents(val)
+}
+
+// closeContainer closes the container 'name'
+func closeContainer(name string) {
+	val, ok := findContainer(name)
+	if !ok {
+		return
+	}
+
+	if !val.Openable {
+		say("the-doesnt-close", "The %s doesn't close.", name)
+		return
+	}
+
+	if val.Closed {
+		say("the-is-already-closed", "The %s is already closed.", name)
+		return
+	}
+
+	val.Closed = true
+	say("you-close-the", "You close the %s.", name)
+}
+
+// lockContainer locks or unlocks the container 'name' with its key
+func lockContainer(name string, lock bool) {
+	name = strings.Split(name, " with ")[0]
+
+	val, ok := findContainer(name)
+	if !ok {
+		return
+	}
+
+	if val.Key == "" {
+		say("the-has-no-lock", "The %s has no lock.", name)
+		return
+	}
+
+	if _, ok := inventory[val.Key]; !ok {
+		say("you-need-the-for", "You need the %s for that.", val.Key)
+		return
+	}
+
+	switch {
+	case lock && val.Locked:
+		say("the-is-already-locked", "The %s is already locked.", name)
+	case lock:
+		val.Closed = true
+		val.Locked = true
+		say("you-close-the-and", "You close the %s and lock it with the %s.", name, val.Key)
+	case !val.Locked:
+		say("the-isnt-locked", "The %s isn't locked.", name)
+	default:
+		val.Locked = false
+		say("you-unlock-the-with", "You unlock the %s with the %s.", name, val.Key)
+	}
+}
+
+// putItem moves 'item' from the player's inventory into the container 'name'
+func putItem(item string, name string) {
+	val, ok := inventory[item]
+	if !ok {
+		say("is-not-in-your", "%s is not in your backpack.", item)
+		return
+	}
+
+	c, ok := findContainer(name)
+	if !ok {
+		return
+	}
+
+	if c == val || holds(val, c) {
+		say("that-would-be-a", "That would be a neat trick.")
+		return
+	}
+
+	if c.Closed {
+		say("the-is-closed", "The %s is closed.", name)
+		return
+	}
+
+	if !belongsIn(item, c) {
+		say("the-doesnt-go-in", "The %s doesn't go in the %s.", item, name)
+		return
+	}
+
+	// putting it in something left in the room is as good as dropping it
+	if !anywhere(inventory, c.Name) && !canLetGo(val) {
+		return
+	}
+
+	if c.Contents == nil {
+		c.Contents = make(map[string]*Item)
+	}
+	c.Contents[item] = val
+	delete(inventory, item)
+	inventoryChanged()
+	say("you-put-the-in", "You put the %s in the %s.", item, name)
+	assemble(true)
+}
+
+// takeFrom takes 'item' out of the container 'name'
+func takeFrom(item string, name string) {
+	c, ok := findContainer(name)
+	if !ok {
+		return
+	}
+
+	if c.Closed {
+		say("the-is-closed", "The %s is closed.", name)
+		return
+	}
+
+	val, ok := c.Contents[item]
+	if !ok || !val.Discovered {
+		say("there-is-no-in", "There is no %s in the %s.", item, name)
+		return
+	}
+
+	// the same rules as taking things from the room
+	if val.IsFeature {
+		say("you-cannot-pick-that", "You cannot pick that up!")
+		return
+	}
+
+	if val.tooBig() {
+		say("is-too-big-to", "%s is too big to pick up!\nWhy don't you try to {verb:shrink} it first?", item)
+		return
+	}
+
+	if !carried(c) && !canCarry(inventory, val) {
+		return
+	}
+
+	inventory[item] = val
+	delete(c.Contents, item)
+	inventoryChanged()
+	say("you-take-the-out", "You take the %s out of the %s.\nIt is now in your {verb:inventory}.", item, name)
+}
+
+// splitAt splits the words 'w' at the first of 'seps', returning the words
+// either side of it
+func splitAt(w []string, seps ...string) (string, string, bool) {
+	for i, word := range w {
+		for _, sep := range seps {
+			if word == sep {
+				return strings.Join(w[:i], " "), strings.Join(w[i+1:], " "), true
+			}
+		}
+	}
+
+	return strings.Join(w, " "), "", false
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// box returns an open box holding 'contents', to put in a room
+func box(contents map[string]*Item) *Item {
+	return &Item{Name: "box", Size: 3, Discovered: true, IsContainer: true, Contents: contents}
+}
+
+func TestContainersInTheDarkCantBeSeen(t *testing.T) {
+	playIn("Basement Lab")
+	curRoom.Items["box"] = box(map[string]*Item{"rock": {Name: "rock", Weight: 1, Discovered: true}})
+
+	var said strings.Builder
+	out = &said
+	parseCommand("look in box")
+	out = ioutil.Discard
+	if !strings.Contains(said.String(), "too dark") {
+		t.Errorf("looking in the box in the dark said:\n%s", said.String())
+	}
+	if _, ok := findContainer("box"); ok {
+		t.Error("found the box in the dark")
+	}
+	parseCommand("take rock from box")
+	if _, ok := inventory["rock"]; ok {
+		t.Error("took the rock from the box in the dark")
+	}
+
+	candle := copyItem(catalog["candle"])
+	candle.Lit = true
+	inventory["candle"] = candle
+	parseCommand("take rock from box")
+	if _, ok := inventory["rock"]; !ok {
+		t.Error("couldn't take the rock from the box by candlelight")
+	}
+}
+
+func TestTakeFromLeavesHiddenThingsAndFeatures(t *testing.T) {
+	playIn("Kitchen")
+	curRoom.Items["box"] = box(map[string]*Item{
+		"rock":  {Name: "rock", Weight: 1},
+		"shelf": {Name: "shelf", Weight: 1, Discovered: true, IsFeature: true},
+	})
+
+	for _, name := range []string{"rock", "shelf"} {
+		parseCommand("take " + name + " from box")
+		if _, ok := inventory[name]; ok {
+			t.Errorf("took the %s from the box", name)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// a way of making something out of other things. Recipes without Into are
+// made by combining their inputs; the rest are assembled by putting each
+// input into the Into item, and are made once it holds them all.
+type Recipe struct {
+	Name    string
+	Inputs  []string // items used up in making the output
+	Tool    string   // an item the player must have, which isn't used up
+	Room    string   // where the output must be made; anywhere if empty
+	Into    string   // the item the inputs are put into
+	Output  *Item    // what is made
+	Message string
+	Ready   string // said when an assembly is complete but the player is in the wrong room
+	Wins    bool   // making the output wins the game
+}
+
+var recipes = make(map[string]*Recipe) // map of recipes, by name
+
+// loadRecipes reads recipe definitions from the 'recipes' directory relative
+// to the game's home directory.
+func loadRecipes() {
+	files, e := ioutil.ReadDir("recipes")
+	if e != nil {
+		log.Fatal(e)
+	}
+
+	for _, f := range files {
+		matched, _ := regexp.MatchString(`\.json`, f.Name())
+		if matched == true {
+			recipeJson, e := ioutil.ReadFile("recipes/" + f.Name())
+
+			if e != nil {
+				log.Fatal(e)
+			}
+
+			var r Recipe
+			if e := json.Unmarshal([]byte(recipeJson), &r); e != nil {
+				log.Fatalf("recipes/%s: %v", f.Name(), e)
+			}
+			recipes[r.Name] = &r
+		}
+	}
+
+	for _, r := range recipes {
+		if r.Output == nil {
+			panic(fmt.Sprintf("recipe %s doesn't make anything", r.Name))
+		}
+	}
+
+	localizeRecipes(recipes)
+	for _, r := range recipes {
+		addToCatalog(map[string]*Item{r.Output.Name: r.Output})
+	}
+
+	for _, r := range recipes {
+		for _, item := range append(r.Inputs, r.Tool, r.Into) {
+			if _, ok := catalog[item]; item != "" && item != "shrink ray" && !ok {
+				panic(fmt.Sprintf("recipe %s needs an item that doesn't exist: %s", r.Name, item))
+			}
+		}
+
+		if _, ok := rooms[r.Room]; r.Room != "" && !ok {
+			panic(fmt.Sprintf("recipe %s is made in a room that doesn't exist: %s", r.Name, r.Room))
+		}
+	}
+}
+
+// parts lists the items which went into making 'item', including 'item'
+// itself and anything inside it
+func parts(item *Item) []string {
+	p := append([]string{item.Name}, item.Parts...)
+	for _, val := range item.Contents {
+		p = append(p, parts(val)...)
+	}
+
+	return p
+}
+
+// canMake reports whether the player can make recipe 'r' here, given that
+// they have its inputs. If not, and 'tell' is true, it says why.
+func (r *Recipe) canMake(tell bool) bool {
+	if _, ok := inventory[r.Tool]; r.Tool != "" && !ok {
+		if tell {
+			say("youll-need-the-for", "You'll need the %s for that.", r.Tool)
+		}
+		return false
+	}
+
+	if r.Room != "" && curRoom.Name != r.Room {
+		if tell && r.Ready != "" {
+			narrate(r.Ready)
+		} else if tell {
+			say("you-cant-do-that", "You can't do that here. Try the %s.", strings.ToLower(localName(r.Room)))
+		}
+		return false
+	}
+
+	return true
+}
+
+// make turns the items in 'used' into the output of recipe 'r', which goes
+// into the player's inventory. The caller takes the used items away.
+func (r *Recipe) make(used []*Item) {
+	b, e := json.Marshal(r.Output)
+	if e != nil {
+		log.Fatal(e)
+	}
+
+	var made Item
+	if e := json.Unmarshal(b, &made); e != nil {
+		log.Fatal(e)
+	}
+
+	for _, item := range used {
+		made.Parts = append(made.Parts, parts(item)...)
+	}
+	for _, p := range made.Parts {
+		flags[usedFlag(p)] = true
+	}
+
+	narrate(r.Message)
+	inventory[made.Name] = &made
+	inventoryChanged()
+
+	if r.Wins {
+		gameOver = true
+	}
+}
+
+// combineItems makes something out of the items named in 'what', such as
+// "sand and candle"
+func combineItems(what string) {
+	what = strings.NewReplacer(",", " and ", " with ", " and ").Replace(" " + what + " ")
+
+	var names []string
+	for _, name := range strings.Split(what, " and ") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) < 2 {
+		say("combine-what-with-what", "Combine what with what? Try: combine <item> and <item>")
+		return
+	}
+
+	var used []*Item
+	for _, name := range names {
+		val, ok := inventory[name]
+		if !ok {
+			say("is-not-in-your", "%s is not in your backpack.", name)
+			return
+		}
+		used = append(used, val)
+	}
+
+	for _, r := range recipes {
+		if r.Into == "" && sameItems(r.Inputs, names) {
+			if r.canMake(true) {
+				for _, name := range names {
+					delete(inventory, name)
+				}
+				inventoryChanged()
+				r.make(used)
+			}
+			return
+		}
+	}
+
+	say("you-fiddle-with-them", "You fiddle with them for a while, but nothing useful comes of it.")
+}
+
+// sameItems reports whether 'a' and 'b' name the same items, in any order
+func sameItems(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for _, x := range a {
+		found := false
+		for _, y := range b {
+			found = found || x == y
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// belongsIn reports whether 'item' may be put into 'c'. Items which are put
+// together by recipes only take their own inputs.
+func belongsIn(item string, c *Item) bool {
+	assembly := false
+	for _, r := range recipes {
+		if r.Into == c.Name {
+			assembly = true
+			for _, input := range r.Inputs {
+				if input == item {
+					return true
+				}
+			}
+		}
+	}
+
+	return !assembly
+}
+
+// assemble finishes any assembly the player is carrying which has everything
+// put into it, if they're in the right place. If 'tell' is true, the player
+// hears why an assembly with everything in it can't be finished yet.
+func assemble(tell bool) {
+	for _, r := range recipes {
+		c, ok := inventory[r.Into]
+		if r.Into == "" || !ok {
+			continue
+		}
+
+		complete := true
+		for _, input := range r.Inputs {
+			_, ok := c.Contents[input]
+			complete = complete && ok
+		}
+
+		if complete && r.canMake(tell) {
+			delete(inventory, c.Name)
+			inventoryChanged()
+			r.make([]*Item{c})
+		}
+	}
+}
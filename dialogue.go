@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// a conversation the player can have with a character
+type Dialogue struct {
+	Name  string // who the player talks to
+	Phone bool   // the character can be reached from anywhere
+	Start string
+	Nodes map[string]*DialogueNode
+}
+
+// one step of a conversation: what the character says, and what the player
+// may say back. A node without choices ends the conversation.
+type DialogueNode struct {
+	Text    string
+	Says    []*Line // the first line whose condition holds is said after Text
+	Effects *Effects
+	Choices []*Choice
+}
+
+// something a character says only under some condition
+type Line struct {
+	If   *Condition
+	Text string
+}
+
+// something the player may say. A choice without a Next node ends the
+// conversation.
+type Choice struct {
+	Text    string
+	If      *Condition
+	Next    string
+	Effects *Effects
+}
+
+// a condition on the game which must hold for a line or choice to be used
+type Condition struct {
+	Has      []string // items the player must be carrying, even put away
+	Lacks    []string // items the player must never have found
+	Flags    []string // flags which must be set
+	NotFlags []string // flags which must not be set
+	Sizes    []int    // sizes the player may be; any size if empty
+}
+
+// what happens to the game when a node is reached or a choice is made
+type Effects struct {
+	Set    []string // flags to set
+	Clear  []string // flags to clear
+	Cost   int      // points taken from the player's score
+	State  string   // the new state of the character being talked to
+	React  string   // how the character being talked to reacts
+	GiveUp bool     // the player gives up the game
+}
+
+// where the player is in a conversation
+type Conversation struct {
+	Dialogue string
+	Node     string
+}
+
+var dialogues = make(map[string]*Dialogue) // map of dialogues, by character
+var talking *Conversation                  // the conversation the player is in, if any
+var flags = make(map[string]bool)          // things that have happened in the game
+var penalty int                            // points the player has spent
+
+// loadDialogues reads dialogue definitions from the 'dialogues' directory
+// relative to the game's home directory.
+func loadDialogues() {
+	files, e := ioutil.ReadDir("dialogues")
+	if e != nil {
+		log.Fatal(e)
+	}
+
+	for _, f := range files {
+		matched, _ := regexp.MatchString(`\.json`, f.Name())
+		if matched == true {
+			dialogueJson, e := ioutil.ReadFile("dialogues/" + f.Name())
+
+			if e != nil {
+				log.Fatal(e)
+			}
+
+			var d Dialogue
+			if e := json.Unmarshal([]byte(dialogueJson), &d); e != nil {
+				log.Fatalf("dialogues/%s: %v", f.Name(), e)
+			}
+			dialogues[d.Name] = &d
+		}
+	}
+
+	for _, d := range dialogues {
+		if _, ok := d.Nodes[d.Start]; !ok {
+			panic(fmt.Sprintf("dialogue with %s starts at a node that doesn't exist: %s", d.Name, d.Start))
+		}
+
+		for _, n := range d.Nodes {
+			for _, c := range n.Choices {
+				if _, ok := d.Nodes[c.Next]; c.Next != "" && !ok {
+					panic(fmt.Sprintf("dialogue with %s goes to a node that doesn't exist: %s", d.Name, c.Next))
+				}
+			}
+		}
+	}
+
+	localizeDialogues(dialogues)
+}
+
+// obtained reports whether the player has 'item', even if it's put away in
+// something they're carrying or has been used to make something
+func obtained(item string) bool {
+	return anywhere(inventory, item) || flags[usedFlag(item)]
+}
+
+// usedFlag is the flag set once 'item' has been used to make something
+func usedFlag(item string) string {
+	return "used " + item
+}
+
+// holds reports whether condition 'c' holds in the current game
+func (c *Condition) holds() bool {
+	if c == nil {
+		return true
+	}
+
+	for _, item := range c.Has {
+		if !anywhere(inventory, item) {
+			return false
+		}
+	}
+
+	for _, item := range c.Lacks {
+		if obtained(item) {
+			return false
+		}
+	}
+
+	for _, f := range c.Flags {
+		if !flags[f] {
+			return false
+		}
+	}
+
+	for _, f := range c.NotFlags {
+		if flags[f] {
+			return false
+		}
+	}
+
+	if len(c.Sizes) == 0 {
+		return true
+	}
+
+	for _, size := range c.Sizes {
+		if size == playerSize() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// choices lists the choices the player currently has at node 'n'
+func (n *DialogueNode) choices() []*Choice {
+	var c []*Choice
+	for _, choice := range n.Choices {
+		if choice.If.holds() {
+			c = append(c, choice)
+		}
+	}
+
+	return c
+}
+
+// canTalkTo reports whether the character 'name' can hear the player
+func canTalkTo(d *Dialogue) bool {
+	if d.Phone {
+		return true
+	}
+
+	if n, ok := npcs[d.Name]; ok {
+		return n.Room == curRoom.Name
+	}
+
+	_, ok := encounterHere(d.Name)
+	return ok
+}
+
+// talkTo starts a conversation with the character 'name'
+func talkTo(name string) {
+	name = strings.TrimPrefix(name, "with ")
+
+	d, ok := dialogues[name]
+	if !ok || !canTalkTo(d) {
+		say("theres-nobody-called-here", "There's nobody called %s here to talk to.", name)
+		return
+	}
+
+	talking = &Conversation{Dialogue: d.Name, Node: d.Start}
+	showNode()
+}
+
+// showNode prints the part of the conversation the player has reached,
+// ending the conversation if there is nothing left to say
+func showNode() {
+	d := dialogues[talking.Dialogue]
+	n := d.Nodes[talking.Node]
+
+	narrate(n.Text)
+	for _, l := range n.Says {
+		if l.If.holds() {
+			narrate(l.Text)
+			break
+		}
+	}
+
+	room := curRoom
+	applyEffects(d, n.Effects)
+	if talking == nil || curRoom != room {
+		talking = nil
+		return
+	}
+
+	c := n.choices()
+	if len(c) == 0 {
+		talking = nil
+		return
+	}
+
+	narrate("")
+	for i, choice := range c {
+		cost := ""
+		if choice.Effects != nil && choice.Effects.Cost == 1 {
+			cost = text("costs-a-point", " (costs a point)")
+		} else if choice.Effects != nil && choice.Effects.Cost > 1 {
+			cost = text("costs-points", " (costs %d points)", choice.Effects.Cost)
+		}
+		narrate(fmt.Sprintf("     %d. %s%s", i+1, choice.Text, cost))
+	}
+	say("type-the-number-of", "Type the number of what you want to say, or {verb:bye}.")
+}
+
+// continueDialogue carries on the conversation with what the player said
+func continueDialogue(action string) {
+	d := dialogues[talking.Dialogue]
+	c := d.Nodes[talking.Node].choices()
+
+	if f := strings.Fields(action); len(f) > 0 && (f[0] == "bye" || f[0] == "goodbye") {
+		say("you-end-the-conversation", "You end the conversation.")
+		talking = nil
+		return
+	}
+
+	i, e := strconv.Atoi(strings.TrimSpace(action))
+	if e != nil || i < 1 || i > len(c) {
+		say("type-a-number-from", "Type a number from 1 to %d, or {verb:bye}.", len(c))
+		return
+	}
+
+	choice := c[i-1]
+	narrate(fmt.Sprintf("\"%s\"\n", choice.Text))
+
+	room := curRoom
+	applyEffects(d, choice.Effects)
+	if talking == nil || curRoom != room || choice.Next == "" {
+		talking = nil
+		return
+	}
+
+	talking.Node = choice.Next
+	showNode()
+}
+
+// applyEffects changes the game as the conversation 'd' requires
+func applyEffects(d *Dialogue, e *Effects) {
+	if e == nil {
+		return
+	}
+
+	for _, f := range e.Set {
+		flags[f] = true
+	}
+
+	for _, f := range e.Clear {
+		delete(flags, f)
+	}
+
+	penalty += e.Cost
+	statusChanged()
+
+	if e.State != "" {
+		if n, ok := npcs[d.Name]; ok {
+			n.setState(e.State)
+		} else if enc, ok := encounters[d.Name]; ok {
+			enc.setState(e.State)
+		}
+	}
+
+	if e.React != "" {
+		if enc, ok := encounterHere(d.Name); ok {
+			enc.react(e.React)
+		}
+	}
+
+	if e.GiveUp {
+		talking = nil
+		callYourParents()
+	}
+}
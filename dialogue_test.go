@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestConditionCountsItemsPutAwayOrUsed(t *testing.T) {
+	restoreGame(newGame())
+	lacksThread := &Condition{Lacks: []string{"thread"}}
+	hasThread := &Condition{Has: []string{"thread"}}
+
+	if !lacksThread.holds() || hasThread.holds() {
+		t.Fatal("a new game hasn't found the thread")
+	}
+
+	inventory["backpack"].Contents = map[string]*Item{"thread": copyItem(catalog["thread"])}
+	if lacksThread.holds() || !hasThread.holds() {
+		t.Error("the thread in the backpack wasn't counted")
+	}
+
+	inventory["sand"] = copyItem(catalog["sand"])
+	inventory["candle"] = copyItem(catalog["candle"])
+	used := []*Item{inventory["sand"], inventory["candle"]}
+	delete(inventory, "sand")
+	delete(inventory, "candle")
+	recipes["lens"].make(used)
+
+	if (&Condition{Lacks: []string{"candle"}}).holds() {
+		t.Error("the candle melted into the lens wasn't counted")
+	}
+	if (&Condition{Has: []string{"candle"}}).holds() {
+		t.Error("the candle melted into the lens can't be had any more")
+	}
+}
+
+// copyItem returns a copy of 'item', to give the player
+func copyItem(item *Item) *Item {
+	c := *item
+	c.Discovered = true
+	return &c
+}
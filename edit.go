@@ -0,0 +1,690 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the way back through an exit going each direction
+var opposite = map[string]string{
+	"north": "south", "south": "north",
+	"east": "west", "west": "east",
+	"up": "down", "down": "up",
+	"in": "out", "out": "in",
+}
+
+// fields written to room files even when they're empty, so every item reads
+// the same way
+var alwaysWritten = map[string]bool{
+	"name": true, "description": true, "size": true, "weight": true,
+	"isFeature": true, "discovered": true, "containsHiddenObject": true,
+	"discoveryStatement": true, "hiddenObject": true, "visited": true,
+	"floor": true, "x": true, "y": true, "items": true,
+}
+
+const editHelp = `Commands:
+
+	rooms :: List the rooms.
+
+	room <name> :: Work on a room, making it if it's new.
+
+	longdesc <text> :: What the player is told the first time they come in.
+	desc <text> :: What the player is told when they come back.
+		Write \n for a new line, and mark up words like {item:rug}.
+
+	light bright|dim|dark :: Whether the player needs a light in here.
+
+	place <floor> <x> <y> :: Where the room goes on the map.
+
+	alias <pattern> :: Another name for the room, as a regular expression.
+
+	link <room> [<direction>] :: Join this room and <room> both ways,
+		going <direction> from here and the opposite way back.
+
+	unlink <room> :: Take away the way between this room and <room>.
+
+	exitalias <room> = <name>, <name> :: Other names for the way to <room>.
+
+	item <name> = <description> :: Put an item in the room, or describe
+		it again.
+
+	feature <item> :: Make an item part of the room, so it can't be taken.
+
+	size <item> = <size> :: How big an item is, from microscopic to huge.
+
+	weight <item> = <weight> :: How heavy an item is.
+
+	hide <item> in <item> = <text> :: Hide an item, to be found by looking
+		at the other one. The text tells the player what they've found.
+
+	remove <item> :: Take an item out of the room.
+
+	preview :: Show the room as the player will see it.
+
+	check :: List anything wrong with the rooms.
+
+	save :: Check the rooms, and write the ones which have changed.
+
+	quit :: Stop editing.`
+
+// an editing session over the room definitions in a directory
+type worldEditor struct {
+	dir     string
+	rooms   map[string]*Room
+	files   map[string]string // the file each room is kept in
+	room    *Room             // the room being worked on
+	changed map[string]bool   // rooms which need writing out
+	warned  bool              // the author has been told there are unsaved changes
+}
+
+// editCommand runs "adventure edit", which creates and changes rooms
+func editCommand(args []string) {
+	fs := flag.NewFlagSet("edit", flag.ExitOnError)
+	dir := fs.String("dir", "rooms", "edit the rooms in this directory")
+	fs.Parse(args)
+
+	m, files, e := readRooms(*dir)
+	if e != nil {
+		log.Fatal(e)
+	}
+
+	ed := &worldEditor{dir: *dir, rooms: m, files: files, changed: make(map[string]bool)}
+	out = os.Stdout
+
+	fmt.Fprintf(out, "Editing %d rooms in %s. Type 'help' for the commands.\n", len(m), *dir)
+	fmt.Fprint(out, "\nedit> ")
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if !ed.run(strings.TrimSpace(scanner.Text())) {
+			return
+		}
+		fmt.Fprint(out, "\nedit> ")
+	}
+}
+
+// run carries out a single editing command. It returns false when the author
+// has finished.
+func (ed *worldEditor) run(line string) bool {
+	if line == "" {
+		return true
+	}
+
+	cmd, arg := line, ""
+	if i := strings.Index(line, " "); i >= 0 {
+		cmd, arg = line[:i], strings.TrimSpace(line[i+1:])
+	}
+
+	switch cmd {
+	case "help":
+		fmt.Fprintln(out, editHelp)
+		return true
+	case "rooms":
+		ed.listRooms()
+		return true
+	case "room":
+		ed.selectRoom(arg)
+		return true
+	case "check":
+		if !ed.check() {
+			fmt.Fprintln(out, "Everything looks fine.")
+		}
+		return true
+	case "save":
+		ed.save()
+		return true
+	case "quit":
+		if len(ed.changed) > 0 && !ed.warned {
+			fmt.Fprintln(out, "There are unsaved changes. Type 'save' to keep them, or 'quit' again to throw them away.")
+			ed.warned = true
+			return true
+		}
+		return false
+	}
+
+	if ed.room == nil {
+		fmt.Fprintln(out, "Choose a room to work on first, with 'room <name>'.")
+		return true
+	}
+
+	r := ed.room
+	switch cmd {
+	case "longdesc":
+		r.LongDesc = unescape(arg)
+	case "desc":
+		r.Description = unescape(arg)
+	case "light":
+		if arg != "bright" && arg != Dim && arg != Dark {
+			fmt.Fprintln(out, "A room may be bright, dim or dark.")
+			return true
+		}
+		r.Light = arg
+		if arg == "bright" {
+			r.Light = ""
+		}
+	case "place":
+		f := strings.Fields(arg)
+		var n [3]int
+		for i := range n {
+			if i >= len(f) {
+				fmt.Fprintln(out, "Give a floor, then how far across and down the room is, like 'place 0 1 2'.")
+				return true
+			}
+			v, e := strconv.Atoi(f[i])
+			if e != nil {
+				fmt.Fprintf(out, "%s isn't a number.\n", f[i])
+				return true
+			}
+			n[i] = v
+		}
+		r.Layout = &Layout{Floor: n[0], X: n[1], Y: n[2]}
+	case "alias":
+		r.Alias = arg
+	case "link":
+		if !ed.link(arg) {
+			return true
+		}
+	case "unlink":
+		if !ed.unlink(arg) {
+			return true
+		}
+	case "exitalias":
+		if !ed.exitAlias(arg) {
+			return true
+		}
+	case "item", "feature", "size", "weight", "remove":
+		if !ed.editItem(cmd, arg) {
+			return true
+		}
+	case "hide":
+		if !ed.hide(arg) {
+			return true
+		}
+	case "preview":
+		ed.preview()
+		return true
+	default:
+		fmt.Fprintf(out, "Not a valid command: %s\n", line)
+		return true
+	}
+
+	ed.changed[r.Name] = true
+	ed.warned = false
+	fmt.Fprintln(out, "OK.")
+
+	return true
+}
+
+// listRooms prints the name of every room, and the ways out of it
+func (ed *worldEditor) listRooms() {
+	var names []string
+	for name := range ed.rooms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		mark := " "
+		if ed.changed[name] {
+			mark = "*"
+		}
+		fmt.Fprintf(out, "%s %s -> %s\n", mark, name, strings.Join(ed.rooms[name].Exits, ", "))
+	}
+}
+
+// selectRoom makes the room called 'name' the one being worked on, creating
+// it if there isn't one
+func (ed *worldEditor) selectRoom(name string) {
+	if name == "" {
+		fmt.Fprintln(out, "Which room?")
+		return
+	}
+
+	// the name becomes the room's file name, which must stay in the rooms
+	// directory
+	if strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		fmt.Fprintln(out, "A room's name can't have /, \\ or .. in it.")
+		return
+	}
+
+	if r := ed.find(name); r != nil {
+		ed.room = r
+		fmt.Fprintf(out, "Working on the %s.\n", r.Name)
+		return
+	}
+
+	ed.room = &Room{Name: name, Items: make(map[string]*Item)}
+	ed.rooms[name] = ed.room
+	ed.changed[name] = true
+	fmt.Fprintf(out, "Made a new room, the %s.\n", name)
+}
+
+// find returns the room called 'name', whatever its case, or nil if there's
+// no such room
+func (ed *worldEditor) find(name string) *Room {
+	for n, r := range ed.rooms {
+		if strings.EqualFold(n, name) {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// link joins the room being worked on to another both ways. 'arg' is the
+// other room, and optionally the direction it is from here.
+func (ed *worldEditor) link(arg string) bool {
+	name, dir := arg, ""
+	if f := strings.Fields(arg); len(f) > 1 {
+		if d, ok := directions[f[len(f)-1]]; ok && ed.find(strings.Join(f[:len(f)-1], " ")) != nil {
+			name, dir = strings.Join(f[:len(f)-1], " "), d
+		}
+	}
+
+	other := ed.find(name)
+	if other == nil {
+		fmt.Fprintf(out, "There's no room called %s. Make it first with 'room %s'.\n", name, name)
+		return false
+	}
+	if other == ed.room {
+		fmt.Fprintln(out, "A room can't lead to itself.")
+		return false
+	}
+
+	for _, pair := range [][2]*Room{{ed.room, other}, {other, ed.room}} {
+		if p := wayInDirection(pair[0], dir); p != nil && p.To != pair[1].Name {
+			fmt.Fprintf(out, "The %s already goes %s to the %s.\n", pair[0].Name, dir, p.To)
+			return false
+		}
+		dir = opposite[dir]
+	}
+
+	addWay(ed.room, other.Name, dir)
+	addWay(other, ed.room.Name, opposite[dir])
+	ed.changed[other.Name] = true
+
+	return true
+}
+
+// wayInDirection returns the passage out of 'r' going 'dir', or nil if there
+// isn't one
+func wayInDirection(r *Room, dir string) *Exit {
+	if dir == "" {
+		return nil
+	}
+
+	for _, p := range r.Passages {
+		if p.Direction == dir {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// addWay makes an exit from 'r' to the room called 'to', going 'dir' if it's
+// not empty
+func addWay(r *Room, to string, dir string) {
+	found := false
+	for _, e := range r.Exits {
+		found = found || e == to
+	}
+	if !found {
+		r.Exits = append(r.Exits, to)
+	}
+
+	if dir == "" {
+		return
+	}
+
+	for _, p := range r.Passages {
+		if p.Via == "" && p.To == to {
+			p.Direction = dir
+			return
+		}
+	}
+	r.Passages = append(r.Passages, &Exit{To: to, Direction: dir})
+}
+
+// unlink takes away the ways between the room being worked on and the room
+// called 'name'
+func (ed *worldEditor) unlink(name string) bool {
+	other := ed.find(name)
+	if other == nil {
+		fmt.Fprintf(out, "There's no room called %s.\n", name)
+		return false
+	}
+
+	removeWay(ed.room, other.Name)
+	removeWay(other, ed.room.Name)
+	ed.changed[other.Name] = true
+
+	return true
+}
+
+// removeWay takes away the exit from 'r' to the room called 'to'. Passages
+// through features, like a laundry chute, are left alone.
+func removeWay(r *Room, to string) {
+	var exits []string
+	for _, e := range r.Exits {
+		if e != to {
+			exits = append(exits, e)
+		}
+	}
+	r.Exits = exits
+
+	var passages []*Exit
+	for _, p := range r.Passages {
+		if p.Via != "" || p.To != to {
+			passages = append(passages, p)
+		}
+	}
+	r.Passages = passages
+}
+
+// exitAlias gives the way from the room being worked on to another room the
+// other names in 'arg', like "Pantry = in, curtain"
+func (ed *worldEditor) exitAlias(arg string) bool {
+	name, names, ok := split(arg)
+	other := ed.find(name)
+	if !ok || other == nil {
+		fmt.Fprintln(out, "Name a room this one leads to, then its other names, like 'exitalias Pantry = in, curtain'.")
+		return false
+	}
+
+	var aliases []string
+	for _, a := range strings.Split(names, ",") {
+		if a = strings.ToLower(strings.TrimSpace(a)); a != "" {
+			aliases = append(aliases, a)
+		}
+	}
+
+	for _, p := range ed.room.Passages {
+		if p.Via == "" && p.To == other.Name {
+			p.Aliases = aliases
+			return true
+		}
+	}
+
+	for _, e := range ed.room.Exits {
+		if e == other.Name {
+			ed.room.Passages = append(ed.room.Passages, &Exit{To: e, Aliases: aliases})
+			return true
+		}
+	}
+
+	fmt.Fprintf(out, "The %s doesn't lead to the %s. Join them with 'link %s'.\n", ed.room.Name, other.Name, other.Name)
+	return false
+}
+
+// editItem carries out the item command 'cmd' on the room being worked on
+func (ed *worldEditor) editItem(cmd string, arg string) bool {
+	items := ed.room.Items
+	if items == nil {
+		items = make(map[string]*Item)
+		ed.room.Items = items
+	}
+
+	name, value, hasValue := split(arg)
+	name = strings.ToLower(name)
+
+	if cmd == "item" {
+		if !hasValue || name == "" {
+			fmt.Fprintln(out, "Name the item and describe it, like 'item rug = The rug is old and unraveling.'")
+			return false
+		}
+		if item, ok := items[name]; ok {
+			item.Description = unescape(value)
+		} else {
+			items[name] = &Item{Name: name, Description: unescape(value), Size: Small, Weight: 1, Discovered: true}
+		}
+		return true
+	}
+
+	item, ok := items[name]
+	if !ok {
+		fmt.Fprintf(out, "There's no %s in the %s.\n", name, ed.room.Name)
+		return false
+	}
+
+	switch cmd {
+	case "feature":
+		item.IsFeature = true
+	case "remove":
+		delete(items, name)
+	case "size":
+		for i, s := range sizeNames {
+			if s == strings.ToLower(value) {
+				value = strconv.Itoa(i)
+			}
+		}
+		n, e := strconv.Atoi(value)
+		if !hasValue || e != nil || n < Tiny || n > Huge {
+			fmt.Fprintln(out, "Sizes go from tiny to huge, or 1 to 5.")
+			return false
+		}
+		item.Size = n
+	case "weight":
+		n, e := strconv.Atoi(value)
+		if !hasValue || e != nil || n < 0 {
+			fmt.Fprintln(out, "A weight is a whole number, like 'weight rug = 20'.")
+			return false
+		}
+		item.Weight = n
+	}
+
+	return true
+}
+
+// hide hides an item in the room being worked on, to be found by looking at
+// another. 'arg' is like "screw in refrigerator = That's the {item:screw}!"
+func (ed *worldEditor) hide(arg string) bool {
+	names, statement, ok := split(arg)
+	parts := strings.SplitN(strings.ToLower(names), " in ", 2)
+	if !ok || len(parts) < 2 {
+		fmt.Fprintln(out, "Hide one item in the room in another, like 'hide screw in refrigerator = That's the {item:screw}!'")
+		return false
+	}
+
+	item, found := ed.room.Items[strings.TrimSpace(parts[0])]
+	in, foundIn := ed.room.Items[strings.TrimSpace(parts[1])]
+	if !found || !foundIn || item == in {
+		fmt.Fprintln(out, "Both items must be in the room already. Add them with 'item'.")
+		return false
+	}
+
+	item.Discovered = false
+	in.ContainsHiddenObject = true
+	in.HiddenObject = item.Name
+	in.DiscoveryStatement = unescape(statement)
+
+	return true
+}
+
+// preview shows the room being worked on as the player will see it. The
+// editor's own messages leave their markup alone, so authors can copy it.
+func (ed *worldEditor) preview() {
+	was, wasIn, editorOut := rooms, curRoom, out
+	defer func() {
+		rooms, curRoom, out = was, wasIn, editorOut
+	}()
+	rooms = ed.rooms
+	curRoom = ed.room
+	out = &styler{editorOut, terminalStyle()}
+
+	lookAtRoom()
+	narrate("")
+	listExits()
+	narrate("\nWhen the player comes back:")
+	narrate(curRoom.Description)
+}
+
+// check prints everything wrong with the rooms. It returns false if there's
+// nothing wrong.
+func (ed *worldEditor) check() bool {
+	problems := checkRooms(ed.rooms)
+	for _, p := range problems {
+		fmt.Fprintln(out, p)
+	}
+
+	return len(problems) > 0
+}
+
+// save writes every room which has changed back to its file, if nothing is
+// wrong with the rooms
+func (ed *worldEditor) save() {
+	if ed.check() {
+		fmt.Fprintln(out, "Nothing was saved.")
+		return
+	}
+
+	var names []string
+	for name := range ed.changed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f, ok := ed.files[name]
+		if !ok {
+			f = strings.Replace(strings.ToLower(name), " ", "_", -1) + ".json"
+		}
+
+		b, e := formatRoom(ed.rooms[name])
+		if e == nil {
+			e = ioutil.WriteFile(filepath.Join(ed.dir, f), b, 0644)
+		}
+		if e != nil {
+			fmt.Fprintln(out, e)
+			return
+		}
+
+		ed.files[name] = f
+		delete(ed.changed, name)
+		fmt.Fprintf(out, "Wrote %s.\n", filepath.Join(ed.dir, f))
+	}
+}
+
+// split divides 'arg' at its first " = " into a name and a value. It reports
+// whether there was a value.
+func split(arg string) (string, string, bool) {
+	i := strings.Index(arg, "=")
+	if i < 0 {
+		return strings.TrimSpace(arg), "", false
+	}
+
+	return strings.TrimSpace(arg[:i]), strings.TrimSpace(arg[i+1:]), true
+}
+
+// unescape turns the \n an author types into new lines
+func unescape(s string) string {
+	return strings.Replace(s, `\n`, "\n", -1)
+}
+
+// formatRoom writes 'r' as JSON the way the room files are written: fields in
+// the order they're declared, named in camel case, and left out when they're
+// empty
+func formatRoom(r *Room) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "\t")
+	if e := enc.Encode(ordered(reflect.ValueOf(r))); e != nil {
+		return nil, e
+	}
+
+	return b.Bytes(), nil
+}
+
+// a JSON object whose keys keep their order
+type jsonObject []jsonField
+
+type jsonField struct {
+	key   string
+	value interface{}
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+
+	b.WriteString("{")
+	for i, f := range o {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		if e := enc.Encode(f.key); e != nil {
+			return nil, e
+		}
+		b.WriteString(":")
+		if e := enc.Encode(f.value); e != nil {
+			return nil, e
+		}
+	}
+	b.WriteString("}")
+
+	return b.Bytes(), nil
+}
+
+// ordered turns 'v' into values which encode the way formatRoom describes
+func ordered(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return ordered(v.Elem())
+	case reflect.Struct:
+		var o jsonObject
+		for i := 0; i < v.NumField(); i++ {
+			name := v.Type().Field(i).Name
+			key := strings.ToLower(name[:1]) + name[1:]
+			if f := v.Field(i); !empty(f) || alwaysWritten[key] {
+				o = append(o, jsonField{key, ordered(f)})
+			}
+		}
+		return o
+	case reflect.Map:
+		var keys []string
+		for _, k := range v.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+
+		var o jsonObject
+		for _, k := range keys {
+			o = append(o, jsonField{k, ordered(v.MapIndex(reflect.ValueOf(k)))})
+		}
+		return o
+	case reflect.Slice:
+		s := make([]interface{}, v.Len())
+		for i := range s {
+			s[i] = ordered(v.Index(i))
+		}
+		return s
+	}
+
+	return v.Interface()
+}
+
+// empty reports whether 'v' has nothing worth writing
+func empty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+
+	return v.IsZero()
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormatRoomReadsBackTheSame(t *testing.T) {
+	r := &Room{
+		Name:        "Closet",
+		Description: "A <small> closet.",
+		Items:       map[string]*Item{"shoe": {Name: "shoe", Size: 2, Weight: 1, Discovered: true}},
+		Exits:       []string{"Hallway"},
+		Passages:    []*Exit{{To: "Hallway", Direction: "out"}},
+	}
+
+	b, e := formatRoom(r)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !strings.HasPrefix(string(b), "{\n\t\"name\": \"Closet\",") {
+		t.Errorf("the name isn't written first:\n%s", b)
+	}
+	if !strings.Contains(string(b), "<small>") {
+		t.Errorf("the description's markup was escaped:\n%s", b)
+	}
+	if strings.Contains(string(b), "alias") || strings.Contains(string(b), "layout") {
+		t.Errorf("empty fields were written:\n%s", b)
+	}
+
+	var back Room
+	if e := json.Unmarshal(b, &back); e != nil {
+		t.Fatal(e)
+	}
+	if !reflect.DeepEqual(&back, r) {
+		t.Errorf("read back %+v, want %+v", back, *r)
+	}
+}
+
+func TestSelectRoomRefusesPaths(t *testing.T) {
+	out = &bytes.Buffer{}
+	defer func() { out = ioutil.Discard }()
+
+	ed := &worldEditor{rooms: make(map[string]*Room), changed: make(map[string]bool)}
+	for _, name := range []string{"../attic", "rooms/attic", `c:\attic`, ".."} {
+		ed.selectRoom(name)
+		if len(ed.rooms) > 0 || ed.room != nil {
+			t.Errorf("made a room called %q", name)
+		}
+	}
+
+	ed.selectRoom("Broom Closet")
+	if ed.rooms["Broom Closet"] == nil {
+		t.Error("didn't make the broom closet")
+	}
+}
+
+func TestPreviewLeavesTheGameAlone(t *testing.T) {
+	playIn("Kitchen")
+	was, wasIn, wasOut := rooms, curRoom, out
+
+	closet := &Room{Name: "Closet", Description: "A closet.", Items: make(map[string]*Item)}
+	ed := &worldEditor{rooms: map[string]*Room{"Closet": closet}, room: closet}
+	ed.preview()
+
+	if !reflect.DeepEqual(rooms, was) || curRoom != wasIn || out != wasOut {
+		t.Error("the preview didn't put the game back")
+	}
+}
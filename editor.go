@@ -0,0 +1,478 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// keys which don't type a character
+const (
+	keyUp = -(iota + 1)
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyDelete
+	keyPageUp
+	keyPageDown
+	keyWordLeft
+	keyWordRight
+	keyDeleteWord
+	keyUnknown
+)
+
+// how many lines of history are kept between games
+const historyLength = 500
+
+// the verbs the parser understands, for completion
+var verbs = []string{"look", "look at", "look in", "go", "go to", "exits", "map",
+	"light", "blow out", "put out", "take", "grab", "pull", "yank", "put", "open",
+	"close", "lock", "unlock", "drop", "inventory", "shrink", "grow", "whistle",
+	"call", "talk to", "combine", "eat", "rest", "enter", "climb", "use", "taunt", "throw",
+	"slide", "jump", "cut", "pet", "feed", "ride", "savegame", "loadgame", "quit",
+	"help", "repeat", "verbose", "brief", "superbrief"}
+
+// a line the player is typing, with emacs-style editing, history and
+// completion
+type lineEditor struct {
+	line    []rune
+	pos     int      // where the cursor is in 'line'
+	history []string // the lines entered before, oldest first
+	back    int      // how far back through the history the player has gone
+	pending []rune   // the line being typed, kept while looking through the history
+	killed  []rune   // the text last cut, for Ctrl-Y to put back
+}
+
+// newLineEditor returns an editor which remembers the lines entered in
+// earlier games
+func newLineEditor() *lineEditor {
+	ed := &lineEditor{}
+	if b, e := ioutil.ReadFile(historyFile()); e == nil {
+		ed.history = strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	}
+
+	return ed
+}
+
+// historyFile is where the lines the player enters are kept between games
+func historyFile() string {
+	home, e := os.UserHomeDir()
+	if e != nil {
+		return ".adventure_history"
+	}
+
+	return filepath.Join(home, ".adventure_history")
+}
+
+// remember adds 'line' to the history, and saves it for later games
+func (ed *lineEditor) remember(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	if len(ed.history) > 0 && ed.history[len(ed.history)-1] == line {
+		return
+	}
+
+	ed.history = append(ed.history, line)
+	if len(ed.history) > historyLength {
+		ed.history = ed.history[len(ed.history)-historyLength:]
+	}
+
+	_ = ioutil.WriteFile(historyFile(), []byte(strings.Join(ed.history, "\n")+"\n"), 0600)
+}
+
+// readKey reads a key press from 'r', turning escape sequences into the keys
+// above
+func readKey(r *bufio.Reader) (rune, error) {
+	c, _, e := r.ReadRune()
+	if e != nil || c != 27 {
+		return c, e
+	}
+
+	c, _, e = r.ReadRune()
+	if e != nil {
+		return 0, e
+	}
+
+	switch c {
+	case 'b':
+		return keyWordLeft, nil
+	case 'f':
+		return keyWordRight, nil
+	case 127, 8:
+		return keyDeleteWord, nil
+	case '[', 'O':
+	default:
+		return keyUnknown, nil
+	}
+
+	// the rest of a sequence like "[A" or "[5~" or "[1;5C"
+	var seq []byte
+	for {
+		b, e := r.ReadByte()
+		if e != nil {
+			return 0, e
+		}
+		seq = append(seq, b)
+		if b >= 0x40 && b <= 0x7e {
+			break
+		}
+	}
+
+	switch string(seq) {
+	case "A":
+		return keyUp, nil
+	case "B":
+		return keyDown, nil
+	case "C":
+		return keyRight, nil
+	case "D":
+		return keyLeft, nil
+	case "H", "1~", "7~":
+		return keyHome, nil
+	case "F", "4~", "8~":
+		return keyEnd, nil
+	case "3~":
+		return keyDelete, nil
+	case "5~":
+		return keyPageUp, nil
+	case "6~":
+		return keyPageDown, nil
+	case "1;5D", "1;3D":
+		return keyWordLeft, nil
+	case "1;5C", "1;3C":
+		return keyWordRight, nil
+	}
+
+	return keyUnknown, nil
+}
+
+// edit changes the line for key press 'key'. It returns the line once the
+// player presses Enter, and reports whether they've finished typing
+// altogether. 'choices' are the completions to show the player, when Tab
+// can't decide between them.
+func (ed *lineEditor) edit(key rune) (line string, entered bool, eof bool, choices []string) {
+	switch key {
+	case '\r', '\n':
+		line = string(ed.line)
+		ed.remember(line)
+		ed.line, ed.pos, ed.back = nil, 0, 0
+		return line, true, false, nil
+	case 3: // Ctrl-C
+		return "", false, true, nil
+	case 4: // Ctrl-D
+		if len(ed.line) == 0 {
+			return "", false, true, nil
+		}
+		ed.delete(ed.pos, ed.pos+1)
+	case 1, keyHome: // Ctrl-A
+		ed.pos = 0
+	case 5, keyEnd: // Ctrl-E
+		ed.pos = len(ed.line)
+	case 2, keyLeft: // Ctrl-B
+		if ed.pos > 0 {
+			ed.pos--
+		}
+	case 6, keyRight: // Ctrl-F
+		if ed.pos < len(ed.line) {
+			ed.pos++
+		}
+	case keyWordLeft:
+		ed.pos = ed.wordLeft()
+	case keyWordRight:
+		for ed.pos < len(ed.line) && ed.line[ed.pos] == ' ' {
+			ed.pos++
+		}
+		for ed.pos < len(ed.line) && ed.line[ed.pos] != ' ' {
+			ed.pos++
+		}
+	case 127, 8: // Backspace, Ctrl-H
+		if ed.pos > 0 {
+			ed.delete(ed.pos-1, ed.pos)
+		}
+	case keyDelete:
+		ed.delete(ed.pos, ed.pos+1)
+	case 11: // Ctrl-K
+		ed.cut(ed.pos, len(ed.line))
+	case 21: // Ctrl-U
+		ed.cut(0, ed.pos)
+	case 23, keyDeleteWord: // Ctrl-W
+		ed.cut(ed.wordLeft(), ed.pos)
+	case 25: // Ctrl-Y
+		ed.insert(ed.killed)
+	case 16, keyUp: // Ctrl-P
+		ed.recall(ed.back + 1)
+	case 14, keyDown: // Ctrl-N
+		ed.recall(ed.back - 1)
+	case '\t':
+		return "", false, false, ed.complete()
+	default:
+		if key >= ' ' {
+			ed.insert([]rune{key})
+		}
+	}
+
+	return "", false, false, nil
+}
+
+// insert types 'r' at the cursor
+func (ed *lineEditor) insert(r []rune) {
+	line := append([]rune{}, ed.line[:ed.pos]...)
+	line = append(line, r...)
+	ed.line = append(line, ed.line[ed.pos:]...)
+	ed.pos += len(r)
+}
+
+// delete removes the characters from 'from' up to 'to'
+func (ed *lineEditor) delete(from, to int) {
+	if to > len(ed.line) {
+		to = len(ed.line)
+	}
+	if from >= to {
+		return
+	}
+
+	ed.line = append(ed.line[:from], ed.line[to:]...)
+	ed.pos = from
+}
+
+// cut deletes the characters from 'from' up to 'to', keeping them for Ctrl-Y
+func (ed *lineEditor) cut(from, to int) {
+	if from < to {
+		ed.killed = append([]rune{}, ed.line[from:to]...)
+	}
+	ed.delete(from, to)
+}
+
+// wordLeft is where the word before the cursor starts
+func (ed *lineEditor) wordLeft() int {
+	i := ed.pos
+	for i > 0 && ed.line[i-1] == ' ' {
+		i--
+	}
+	for i > 0 && ed.line[i-1] != ' ' {
+		i--
+	}
+
+	return i
+}
+
+// recall replaces the line with the one 'back' lines back in the history,
+// or the line being typed if 'back' is 0
+func (ed *lineEditor) recall(back int) {
+	if back < 0 || back > len(ed.history) {
+		return
+	}
+
+	if ed.back == 0 {
+		ed.pending = ed.line
+	}
+
+	ed.back = back
+	if back == 0 {
+		ed.line = ed.pending
+	} else {
+		ed.line = []rune(ed.history[len(ed.history)-back])
+	}
+	ed.pos = len(ed.line)
+}
+
+// complete finishes the word before the cursor, if only one thing could be
+// meant, or as much of it as all the choices share. It returns the choices
+// when there's more than one.
+func (ed *lineEditor) complete() []string {
+	typed := strings.ToLower(string(ed.line[:ed.pos]))
+
+	// the longest stretch of words at the end of what's been typed which
+	// starts something the player could mean, so that "take dirty s" finishes
+	// the dirty socks
+	var matches []string
+	start := -1
+	for i := 0; i <= len(typed); i++ {
+		if i > 0 && typed[i-1] != ' ' {
+			continue
+		}
+
+		matches = nil
+		for _, c := range completions(i == 0) {
+			if strings.HasPrefix(c, typed[i:]) && c != typed[i:] {
+				matches = append(matches, c)
+			}
+		}
+		if len(matches) > 0 {
+			start = i
+			break
+		}
+	}
+
+	if start < 0 {
+		return nil
+	}
+
+	if len(matches) == 1 {
+		ed.cut(len([]rune(typed[:start])), ed.pos)
+		ed.insert([]rune(matches[0] + " "))
+		return nil
+	}
+
+	shared := []rune(matches[0])
+	for _, m := range matches[1:] {
+		for !strings.HasPrefix(m, string(shared)) {
+			shared = shared[:len(shared)-1]
+		}
+	}
+
+	if word := []rune(typed[start:]); len(shared) > len(word) {
+		ed.insert(shared[len(word):])
+		return nil
+	}
+
+	return matches
+}
+
+var nameableMu sync.Mutex // guards nameable
+var nameable []string     // the items and ways out the player could name after the last command
+
+// noteNameable keeps the items the player can see or is carrying and the ways
+// out of the current room, so what they type can be completed while the game
+// is busy with a command or reloading the rooms. Callers must hold engineMu.
+func noteNameable() {
+	seen := make(map[string]bool)
+	add := func(s string) {
+		seen[strings.ToLower(s)] = true
+	}
+
+	for name, item := range curRoom.Items {
+		if visible(item) {
+			add(name)
+			add(localName(name))
+		}
+	}
+	for name := range inventory {
+		add(name)
+		add(localName(name))
+	}
+	for _, e := range curRoom.Exits {
+		add(e)
+		add(localName(e))
+	}
+
+	nameableMu.Lock()
+	defer nameableMu.Unlock()
+	nameable = sortedKeys(seen)
+}
+
+// completions lists what the player might be typing: the verbs, if it's the
+// first word, or else the items they could name after the last command
+func completions(first bool) []string {
+	if !first {
+		nameableMu.Lock()
+		defer nameableMu.Unlock()
+		return nameable
+	}
+
+	seen := make(map[string]bool)
+	for _, v := range verbs {
+		seen[strings.ToLower(v)] = true
+	}
+	for w := range locale.Words {
+		seen[strings.ToLower(w)] = true
+	}
+
+	return sortedKeys(seen)
+}
+
+// sortedKeys returns the keys of 'seen' in order
+func sortedKeys(seen map[string]bool) []string {
+	var c []string
+	for s := range seen {
+		c = append(c, s)
+	}
+	sort.Strings(c)
+
+	return c
+}
+
+// an io.Reader of the lines the player types at a terminal, edited with a
+// lineEditor. The terminal is only raw while a line is being typed.
+type lineReader struct {
+	ed   *lineEditor
+	keys *bufio.Reader
+	buf  []byte // what's left of the last line, to be read
+}
+
+// newLineReader reads the player's lines from the terminal on standard input
+func newLineReader() *lineReader {
+	return &lineReader{ed: newLineEditor(), keys: bufio.NewReader(os.Stdin)}
+}
+
+func (lr *lineReader) Read(p []byte) (int, error) {
+	if len(lr.buf) == 0 {
+		line, e := lr.readLine()
+		if e != nil {
+			return 0, e
+		}
+		lr.buf = []byte(line + "\n")
+	}
+
+	n := copy(p, lr.buf)
+	lr.buf = lr.buf[n:]
+
+	return n, nil
+}
+
+// readLine lets the player type and edit a line, after whatever prompt is
+// already on the screen
+func (lr *lineReader) readLine() (string, error) {
+	fd := int(os.Stdin.Fd())
+	state, e := term.MakeRaw(fd)
+	if e != nil {
+		return "", e
+	}
+	defer term.Restore(fd, state)
+
+	// the line is redrawn from wherever the prompt left the cursor
+	os.Stdout.WriteString("\x1b7")
+	for {
+		key, e := readKey(lr.keys)
+		if e != nil {
+			os.Stdout.WriteString("\r\n")
+			return "", io.EOF
+		}
+
+		line, entered, eof, choices := lr.ed.edit(key)
+		switch {
+		case eof:
+			os.Stdout.WriteString("\r\n")
+			return "", io.EOF
+		case entered:
+			os.Stdout.WriteString("\r\n")
+			return line, nil
+		case choices != nil:
+			os.Stdout.WriteString("\r\n" + strings.Join(choices, "   ") + "\r\n> \x1b7")
+		}
+
+		lr.draw()
+	}
+}
+
+// draw shows the line being edited, with the cursor where it belongs
+func (lr *lineReader) draw() {
+	ed := lr.ed
+	s := "\x1b8" + string(ed.line) + "\x1b[K"
+	if left := len(ed.line) - ed.pos; left > 0 {
+		s += "\x1b[" + strconv.Itoa(left) + "D"
+	}
+	os.Stdout.WriteString(s)
+}
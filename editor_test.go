@@ -0,0 +1,56 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCompletionsNameWhatThePlayerCanSee(t *testing.T) {
+	playIn("Kitchen")
+	noteNameable()
+
+	names := completions(false)
+	for _, want := range []string{"flashlight", "pantry", "backpack"} {
+		if !contains(names, want) {
+			t.Errorf("%q isn't among the completions %v", want, names)
+		}
+	}
+	if contains(names, "screw") {
+		t.Error("the screw behind the refrigerator can be completed before it's found")
+	}
+
+	if !contains(completions(true), "take") {
+		t.Error("\"take\" isn't among the verbs completed")
+	}
+}
+
+func TestCompletingWhileTheGameIsBusy(t *testing.T) {
+	playIn("Kitchen")
+	done := make(chan bool)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				completions(false)
+			}
+		}
+	}()
+
+	for _, c := range []string{"take flashlight", "go to pantry", "go to kitchen"} {
+		engineMu.Lock()
+		parseCommand(c)
+		noteNameable()
+		engineMu.Unlock()
+	}
+	close(done)
+	wg.Wait()
+
+	if !contains(completions(false), "downstairs hallway") {
+		t.Error("the completions weren't kept up to date")
+	}
+}
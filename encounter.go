@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// an adversary the player must deal with, like the eagle, which changes from
+// state to state as the player acts
+type Encounter struct {
+	Name       string
+	Room       string   // where the encounter is now
+	Rooms      []string // where the encounter may roam
+	RoamChance float64  // how likely it is to roam each turn
+	Arrives    string
+	Leaves     string // %s is the room the encounter leaves for
+	State      string
+	Turns      int // turns spent in the current state
+	States     map[string]*EncounterState
+	reacted    bool // the encounter has reacted to the player this turn
+}
+
+// one state of an encounter, such as the eagle diving at the player
+type EncounterState struct {
+	Here      string // describes the encounter; empty if it isn't around
+	Roams     bool   // the encounter may move between its rooms
+	Lasts     int    // if not zero, how many turns until the Next state
+	Next      string
+	Reactions map[string][]*Reaction // keyed by what the player does
+}
+
+// how an encounter reacts to the player. Reactions are tried in order and
+// the first which applies is used. The player does "look", "taunt", "leave",
+// "use <item>", or nothing in particular, which is a "turn". Any reaction to
+// "leave" stops the player from leaving.
+type Reaction struct {
+	Requires string  // an item the player must have
+	Chance   float64 // if not zero, how likely the reaction is
+	Consumes bool    // the required item is used up
+	Message  string
+	State    string // the next state, if it changes
+	Carry    string // a room the player is carried off to
+}
+
+var encounters = make(map[string]*Encounter) // map of encounters
+var perils map[string]*Encounter             // encounters as they are when a game begins
+
+// loadEncounters reads encounter definitions from the 'encounters' directory
+// relative to the game's home directory.
+func loadEncounters() {
+	files, e := ioutil.ReadDir("encounters")
+	if e != nil {
+		log.Fatal(e)
+	}
+
+	for _, f := range files {
+		matched, _ := regexp.MatchString(`\.json`, f.Name())
+		if matched == true {
+			encounterJson, e := ioutil.ReadFile("encounters/" + f.Name())
+
+			if e != nil {
+				log.Fatal(e)
+			}
+
+			var enc Encounter
+			if e := json.Unmarshal([]byte(encounterJson), &enc); e != nil {
+				log.Fatalf("encounters/%s: %v", f.Name(), e)
+			}
+			encounters[enc.Name] = &enc
+		}
+	}
+
+	for _, enc := range encounters {
+		if _, ok := enc.States[enc.State]; !ok {
+			panic(fmt.Sprintf("%s starts in a state that doesn't exist: %s", enc.Name, enc.State))
+		}
+
+		for _, r := range enc.Rooms {
+			if _, ok := rooms[r]; !ok {
+				panic(fmt.Sprintf("%s roams to a room that doesn't exist: %s", enc.Name, r))
+			}
+		}
+	}
+
+	localizeEncounters(encounters)
+	perils = copyEncounters(encounters)
+}
+
+// copyEncounters returns a deep copy of the encounters in 'm'
+func copyEncounters(m map[string]*Encounter) map[string]*Encounter {
+	b, e := json.Marshal(m)
+	if e != nil {
+		log.Fatal(e)
+	}
+
+	c := make(map[string]*Encounter)
+	if e := json.Unmarshal(b, &c); e != nil {
+		log.Fatal(e)
+	}
+
+	return c
+}
+
+// present reports whether encounter 'enc' is in the current room
+func (enc *Encounter) present() bool {
+	return enc.Room == curRoom.Name && enc.States[enc.State].Here != ""
+}
+
+// setState moves encounter 'enc' to a new state
+func (enc *Encounter) setState(state string) {
+	enc.State = state
+	enc.Turns = 0
+}
+
+// encounterHere returns the encounter called 'name' in the current room
+func encounterHere(name string) (*Encounter, bool) {
+	enc, ok := encounters[name]
+	if !ok || !enc.present() {
+		return nil, false
+	}
+
+	return enc, true
+}
+
+// describeEncounters mentions the encounters in the current room
+func describeEncounters() {
+	for _, name := range encounterNames() {
+		if enc := encounters[name]; enc.present() {
+			narrate(enc.States[enc.State].Here)
+		}
+	}
+}
+
+// react lets encounter 'enc' respond to what the player did. It returns
+// false if the encounter has no reaction.
+func (enc *Encounter) react(action string) bool {
+	for _, r := range enc.States[enc.State].Reactions[action] {
+		if r.Requires != "" {
+			if _, ok := inventory[r.Requires]; !ok {
+				continue
+			}
+		}
+
+		if r.Chance != 0 && dice.Float64() >= r.Chance {
+			continue
+		}
+
+		narrate(r.Message)
+		enc.reacted = true
+
+		if r.Consumes {
+			delete(inventory, r.Requires)
+			inventoryChanged()
+		}
+
+		if r.State != "" {
+			enc.setState(r.State)
+		}
+
+		if r.Carry != "" {
+			climbedUp = false
+			enterRoom(rooms[r.Carry])
+			describeRoom()
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// reactToAll lets every encounter in the current room respond to 'action'.
+// It returns false if none of them did.
+func reactToAll(action string) bool {
+	reacted := false
+	for _, name := range encounterNames() {
+		if enc := encounters[name]; enc.present() && enc.react(action) {
+			reacted = true
+		}
+	}
+
+	return reacted
+}
+
+// encounterNames returns the names of the encounters in order, so they take
+// their turns the same way each time a seeded game is played
+func encounterNames() []string {
+	var names []string
+	for name := range encounters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// advanceEncounters moves every encounter on by one turn. Encounters which
+// have already reacted to the player this turn only count the time passing.
+func advanceEncounters() {
+	for _, name := range encounterNames() {
+		enc := encounters[name]
+		enc.Turns++
+		state := enc.States[enc.State]
+
+		reacted := enc.reacted
+		enc.reacted = false
+
+		if enc.present() && !reacted && enc.react("turn") {
+			enc.reacted = false
+			continue
+		}
+
+		if state.Lasts != 0 && enc.Turns >= state.Lasts {
+			enc.setState(state.Next)
+			continue
+		}
+
+		if state.Roams && len(enc.Rooms) > 1 && dice.Float64() < enc.RoamChance {
+			next := enc.Rooms[dice.Intn(len(enc.Rooms))]
+			if next == enc.Room {
+				continue
+			}
+
+			if enc.Room == curRoom.Name {
+				narrate(fmt.Sprintf("\n"+enc.Leaves, strings.ToLower(localName(next))))
+			} else if next == curRoom.Name {
+				narrate("\n" + enc.Arrives)
+			}
+			enc.Room = next
+		}
+	}
+}
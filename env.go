@@ -0,0 +1,356 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// what a program playing the game can see after each step
+type Observation struct {
+	Text      []string `json:"text"`
+	Room      string   `json:"room"`
+	Items     []string `json:"items"`
+	Exits     []string `json:"exits"`
+	Inventory []string `json:"inventory"`
+}
+
+// how much each kind of progress is worth to an agent. Step is paid on every
+// step and is usually zero or negative, to encourage short games.
+type Rewards struct {
+	ItemCollected  float64 `json:"itemCollected"`  // picking up one of the winningItems
+	RoomDiscovered float64 `json:"roomDiscovered"` // entering a room for the first time
+	Win            float64 `json:"win"`
+	Lose           float64 `json:"lose"`
+	Step           float64 `json:"step"`
+}
+
+var DefaultRewards = Rewards{
+	ItemCollected:  1,
+	RoomDiscovered: 0.1,
+	Win:            10,
+	Lose:           -10,
+}
+
+// an environment for training and benchmarking agents on the game, in the
+// style of OpenAI Gym. It's part of the game's own program, not a package
+// other Go programs can import; agents play it through playProtocol.
+type Env struct {
+	Rewards Rewards
+	Seed    int64
+	Turns   int
+	s       *Session
+}
+
+// newEnv creates an environment paying 'r' for progress. Call Reset before
+// taking the first step.
+func newEnv(r Rewards) *Env {
+	return &Env{Rewards: r}
+}
+
+// Reset starts a new game. The seed decides what the NPCs get up to, so an
+// episode can be replayed exactly, whatever other games are being played.
+func (env *Env) Reset(seed int64) Observation {
+	env.Seed = seed
+	env.Turns = 0
+	env.s = &Session{ID: "env", Game: newGame(), LastUsed: time.Now(), dice: rand.New(rand.NewSource(seed))}
+
+	o := observe(env.s)
+	o.Text = renderLines(strings.Split(strings.Trim(text("opening", openingMessage), "\n"), "\n"), PlainText)
+
+	return o
+}
+
+// Observe describes the game without taking a step
+func (env *Env) Observe() Observation {
+	o := observe(env.s)
+	o.Text = []string{}
+
+	return o
+}
+
+// Step plays 'action' and reports what happened, what it was worth and
+// whether the game is over. Once the game is over every step is worth
+// nothing.
+func (env *Env) Step(action string) (Observation, float64, bool, map[string]interface{}) {
+	if env.s.GameOver {
+		return env.Observe(), 0, true, env.info()
+	}
+
+	items := score(env.s.Game.Inventory)
+	penalty := env.s.Game.Penalty
+	visited := roomsVisited(env.s)
+
+	var text []string
+	if terminalCommand(action) {
+		// these prompt the player on the terminal, which agents don't have
+		text = []string{"That command is not available here."}
+	} else {
+		text = renderLines(playTurn(env.s, action).Output, PlainText)
+	}
+	env.Turns++
+
+	r := env.Rewards.Step
+	r += env.Rewards.ItemCollected * float64(score(env.s.Game.Inventory)-items)
+	r += env.Rewards.RoomDiscovered * float64(roomsVisited(env.s)-visited)
+	r -= env.Rewards.ItemCollected * float64(env.s.Game.Penalty-penalty)
+
+	if env.s.GameOver {
+		if score(env.s.Game.Inventory) == len(winningItems) {
+			r += env.Rewards.Win
+		} else {
+			r += env.Rewards.Lose
+		}
+	}
+
+	o := observe(env.s)
+	o.Text = text
+
+	return o, r, env.s.GameOver, env.info()
+}
+
+// info reports details of the game that are not part of an observation
+func (env *Env) info() map[string]interface{} {
+	return map[string]interface{}{
+		"seed":         env.Seed,
+		"turns":        env.Turns,
+		"score":        score(env.s.Game.Inventory) - env.s.Game.Penalty,
+		"roomsVisited": roomsVisited(env.s),
+		"load":         carrying(env.s.Game.Inventory),
+		"capacity":     capacity(env.s.Game.Inventory),
+		"energy":       maxEnergy - env.s.Game.Tired,
+	}
+}
+
+// ValidActions lists the commands that currently mean something, built from
+// the items in the room, the inventory and the exits.
+func (env *Env) ValidActions() []string {
+	if env.s.GameOver {
+		return []string{}
+	}
+
+	engineMu.Lock()
+	defer engineMu.Unlock()
+
+	// what the player can do depends on the game in progress
+	g := env.s.Game
+	restoreGame(g)
+
+	if g.Conversation != nil {
+		actions := []string{"bye"}
+		for i := range dialogues[g.Conversation.Dialogue].Nodes[g.Conversation.Node].choices() {
+			actions = append(actions, strconv.Itoa(i+1))
+		}
+
+		return actions
+	}
+
+	if g.Confirming != "" {
+		return []string{"y", "n"}
+	}
+
+	room := g.Rooms[g.CurRoom]
+	have := func(item string) bool {
+		_, ok := g.Inventory[item]
+		return ok
+	}
+
+	actions := []string{"look", "inventory", "map", "call"}
+
+	if g.ClimbedUp {
+		actions = append(actions, "climb down")
+	} else {
+		for _, e := range room.Exits {
+			actions = append(actions, "go "+strings.ToLower(e))
+		}
+	}
+
+	for name, item := range room.Items {
+		if !visible(item) {
+			continue
+		}
+
+		actions = append(actions, "look at "+name)
+
+		switch {
+		case item.IsFeature:
+			if climbable[g.CurRoom] == name && !g.ClimbedUp {
+				actions = append(actions, "climb "+name)
+			}
+		case item.tooBig() && have("shrink ray"):
+			actions = append(actions, "shrink "+name)
+		case !item.tooBig():
+			actions = append(actions, "take "+name)
+		}
+	}
+
+	for i, items := range []map[string]*Item{room.Items, g.Inventory} {
+		for name, c := range items {
+			if !c.IsContainer || !c.Discovered || i == 0 && !visible(c) {
+				continue
+			}
+
+			actions = append(actions, "look in "+name)
+
+			if c.Key != "" && have(c.Key) {
+				if c.Locked {
+					actions = append(actions, "unlock "+name)
+				} else {
+					actions = append(actions, "lock "+name)
+				}
+			}
+
+			if c.Openable && c.Closed && !c.Locked {
+				actions = append(actions, "open "+name)
+			}
+
+			if c.Closed {
+				continue
+			}
+
+			if c.Openable {
+				actions = append(actions, "close "+name)
+			}
+
+			for inside, item := range c.Contents {
+				actions = append(actions, "look at "+inside)
+				if item.tooBig() && have("shrink ray") {
+					actions = append(actions, "shrink "+inside)
+				} else if !item.tooBig() && !item.IsFeature {
+					actions = append(actions, "take "+inside+" from "+name)
+				}
+			}
+
+			for item := range g.Inventory {
+				if item != name && belongsIn(item, c) {
+					actions = append(actions, "put "+item+" in "+name)
+				}
+			}
+		}
+	}
+
+	for _, r := range recipes {
+		ready := r.Into == ""
+		for _, input := range r.Inputs {
+			ready = ready && have(input)
+		}
+
+		if ready {
+			actions = append(actions, "combine "+strings.Join(r.Inputs, " and "))
+		}
+	}
+
+	for name, n := range g.NPCs {
+		if n.Room == g.CurRoom {
+			actions = append(actions, "look at "+name)
+			for verb := range n.Interactions {
+				actions = append(actions, verb+" "+name)
+			}
+			if _, ok := dialogues[name]; ok {
+				actions = append(actions, "talk to "+name)
+			}
+		}
+	}
+
+	for name, item := range g.Inventory {
+		actions = append(actions, "look at "+name, "drop "+name)
+
+		if item.IsEdible {
+			actions = append(actions, "eat "+name)
+		}
+
+		if item.Lit {
+			actions = append(actions, "blow out "+name)
+		} else if item.IsLight && (item.Everlasting || item.Burns > 0 && flame(item) != nil) {
+			actions = append(actions, "light "+name)
+		}
+	}
+
+	if have("dog whistle") {
+		actions = append(actions, "whistle")
+	}
+
+	if have("password") {
+		actions = append(actions, "enter")
+	}
+
+	for name, enc := range g.Encounters {
+		if enc.Room == g.CurRoom && enc.States[enc.State].Here != "" {
+			actions = append(actions, "look at "+name, "taunt "+name)
+			if _, ok := dialogues[name]; ok {
+				actions = append(actions, "talk to "+name)
+			}
+			for action := range enc.States[enc.State].Reactions {
+				if strings.HasPrefix(action, "use ") {
+					actions = append(actions, action)
+				}
+			}
+		}
+	}
+
+	for _, p := range room.Passages {
+		if item, ok := room.Items[p.Via]; p.Via != "" && (!ok || item.Discovered) {
+			actions = append(actions, "jump in "+p.Via)
+		}
+	}
+
+	for _, name := range []string{"copper wire", "couch stuffing"} {
+		if item, ok := room.Items[name]; ok && visible(item) {
+			actions = append(actions, "cut "+name)
+		}
+	}
+
+	// an item can be reached from the room and from a container
+	sort.Strings(actions)
+	var unique []string
+	for i, a := range actions {
+		if i == 0 || a != actions[i-1] {
+			unique = append(unique, a)
+		}
+	}
+
+	return unique
+}
+
+// roomsVisited counts the rooms the player in session 's' has been to
+func roomsVisited(s *Session) int {
+	engineMu.Lock()
+	defer engineMu.Unlock()
+
+	n := 0
+	for _, r := range s.Game.Rooms {
+		if r.Visited {
+			n++
+		}
+	}
+
+	return n
+}
+
+// observe describes what the player in session 's' can currently see
+func observe(s *Session) Observation {
+	engineMu.Lock()
+	state := turnState(s)
+	room := s.Game.Rooms[s.Game.CurRoom]
+
+	o := Observation{
+		Room:      state.Room,
+		Items:     []string{},
+		Exits:     append([]string{}, room.Exits...),
+		Inventory: state.Inventory,
+	}
+
+	restoreGame(s.Game)
+	for name, item := range room.Items {
+		if visible(item) {
+			o.Items = append(o.Items, name)
+		}
+	}
+	engineMu.Unlock()
+
+	sort.Strings(o.Items)
+
+	return o
+}
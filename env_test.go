@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestValidActionsOnlyClimbWhatCanBeClimbed(t *testing.T) {
+	env := newEnv(DefaultRewards)
+	env.Reset(1)
+
+	for name := range world {
+		env.s.Game.CurRoom = name
+		for _, a := range env.ValidActions() {
+			if len(a) > 6 && a[:6] == "climb " && a != "climb down" && climbable[name] != a[6:] {
+				t.Errorf("%s: %q can't be climbed", name, a)
+			}
+		}
+	}
+
+	env.s.Game.CurRoom = "Pantry"
+	if !contains(env.ValidActions(), "climb paper towels") {
+		t.Errorf("the paper towels in the pantry can be climbed")
+	}
+}
+
+func TestValidActionsAreListedOnce(t *testing.T) {
+	env := newEnv(DefaultRewards)
+	env.Reset(1)
+
+	for name := range world {
+		env.s.Game.CurRoom = name
+		seen := make(map[string]bool)
+		for _, a := range env.ValidActions() {
+			if seen[a] {
+				t.Errorf("%s: %q is listed twice", name, a)
+			}
+			seen[a] = true
+		}
+	}
+}
+
+// the NPCs and encounters go the same way in every game with the same seed,
+// however many of them there are
+func TestEnvReplaysSeed(t *testing.T) {
+	was, wasPerils := cast, perils
+	defer func() { cast, perils = was, wasPerils }()
+
+	cast, perils = copyNPCs(was), copyEncounters(wasPerils)
+	for i := 2; i <= 6; i++ {
+		for name, n := range copyNPCs(was) {
+			n.Name = fmt.Sprintf("%s %d", name, i)
+			cast[n.Name] = n
+		}
+		for name, enc := range copyEncounters(wasPerils) {
+			enc.Name = fmt.Sprintf("%s %d", name, i)
+			perils[enc.Name] = enc
+		}
+	}
+
+	where := func(seed int64) []string {
+		env := newEnv(DefaultRewards)
+		env.Reset(seed)
+		for i := 0; i < 80; i++ {
+			env.Step("look")
+		}
+
+		var w []string
+		for _, name := range npcNames() {
+			w = append(w, name+" "+env.s.Game.NPCs[name].Room+" "+env.s.Game.NPCs[name].State)
+		}
+		for _, name := range encounterNames() {
+			w = append(w, name+" "+env.s.Game.Encounters[name].Room)
+		}
+
+		return w
+	}
+
+	first := where(42)
+	for i := 0; i < 5; i++ {
+		if again := where(42); !reflect.DeepEqual(first, again) {
+			t.Fatalf("seed 42 played differently:\n%v\n%v", first, again)
+		}
+	}
+}
+
+// contains reports whether 's' is one of 'list'
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+
+	return false
+}
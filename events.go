@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// the kinds of event the engine reports
+const (
+	Narration        = "narration"  // something the game says
+	RoomDescribed    = "room"       // the description of the room the player is in
+	ItemsSeen        = "items-seen" // the things the player can see in the room
+	InventoryListed  = "inventory-listed"
+	ExitsListed      = "exits-listed"
+	StatusChanged    = "status"    // the room, turns, score and items collected
+	InventoryChanged = "inventory" // what the player is carrying
+	ExitsChanged     = "exits"     // the ways out of the current room
+)
+
+// where the player is and how they're doing
+type Status struct {
+	Room      string
+	Turns     int
+	Score     int
+	Collected int // how many of the winningItems the player has
+	Needed    int // how many of them there are
+}
+
+// a way out of the current room the player knows about
+type Way struct {
+	To        string   // the room it leads to
+	Via       string   // the feature it goes through, if any
+	Direction string   // like "up" or "north", if it has one
+	Aliases   []string // anything else the player can call it
+}
+
+// something the engine tells whoever is showing the game to the player.
+// Names of rooms and items are in English, to be put in the player's language
+// when they're shown.
+type Event struct {
+	Kind     string
+	ID       string   // for Narration, the message said, if it has one
+	Text     string   // for Narration and RoomDescribed
+	Room     string   // for RoomDescribed
+	Items    []string // for ItemsSeen, InventoryListed and InventoryChanged
+	Dim      bool     // for ItemsSeen, when the player might miss something
+	Load     int      // for InventoryListed, how much the player is carrying
+	Capacity int      // for InventoryListed, how much they can manage
+	Exits    []Way    // for ExitsListed and ExitsChanged
+	Status   Status   // for StatusChanged
+}
+
+// everyone who wants to hear about events. Unless something else is showing
+// the game, its text is written to 'out'.
+var listeners = []func(Event){writeText}
+
+var recording *strings.Builder // what the game is saying, while it's kept for "repeat"
+
+// emit tells every listener about event 'e'
+func emit(e Event) {
+	if recording != nil {
+		for _, l := range eventText(e) {
+			recording.WriteString(l + "\n")
+		}
+	}
+
+	for _, l := range listeners {
+		l(e)
+	}
+}
+
+// narrate says 'm', which is already in the player's language
+func narrate(m string) {
+	emit(Event{Kind: Narration, Text: m})
+}
+
+// writeText writes what event 'e' has to say to 'out'
+func writeText(e Event) {
+	for _, l := range eventText(e) {
+		fmt.Fprintln(out, l)
+	}
+}
+
+// eventText returns the lines of text event 'e' shows the player, if any.
+// Events which only report how the game stands have none.
+func eventText(e Event) []string {
+	var lines []string
+	add := func(id string, english string, args ...interface{}) {
+		lines = append(lines, strings.Split(phrase(id, english, args...), "\n")...)
+	}
+
+	switch e.Kind {
+	case Narration, RoomDescribed:
+		return strings.Split(e.Text, "\n")
+
+	case ItemsSeen:
+		if e.Dim {
+			add("its-dim-in-here", "\nIt's dim in here. You might miss something without a light.")
+		}
+
+		if accessible {
+			lines = append(lines, "")
+			if len(e.Items) == 0 {
+				add("you-see-nothing", "You don't see anything here.")
+			} else {
+				add("you-see", "You see %s.", spokenList(theNames(e.Items)))
+			}
+			return lines
+		}
+
+		add("some-of-the-things", "\nSome of the things that you see include:")
+		for _, name := range e.Items {
+			lines = append(lines, "     "+localName(name))
+		}
+
+	case InventoryListed:
+		if accessible {
+			if len(e.Items) == 0 {
+				add("you-arent-carrying-anything", "You aren't carrying anything.")
+			} else {
+				add("you-are-carrying-spoken", "You are carrying %s.", spokenList(theNames(e.Items)))
+			}
+			add("you-can-manage", "That is %d of the %d you can manage.", e.Load, e.Capacity)
+			return lines
+		}
+
+		for _, name := range e.Items {
+			lines = append(lines, localName(name))
+		}
+		add("you-are-carrying-of", "\nYou are carrying %d of the %d you can manage.", e.Load, e.Capacity)
+
+	case ExitsListed:
+		if len(e.Exits) == 0 {
+			add("theres-no-way-out", "There's no way out of here!")
+			return lines
+		}
+
+		if accessible {
+			var ways []string
+			for _, w := range e.Exits {
+				switch {
+				case w.Via != "":
+					ways = append(ways, text("exit-via", "through the %s to the %s", w.Via, w.To))
+				case w.Direction != "":
+					ways = append(ways, text("exit-direction", "%s to the %s", w.Direction, w.To))
+				default:
+					ways = append(ways, text("exit-to", "to the %s", w.To))
+				}
+			}
+			add("you-can-go-spoken", "You can go %s.", spokenList(ways))
+			return lines
+		}
+
+		add("you-can-go-to", "You can go to:")
+		for _, w := range e.Exits {
+			if names := w.names(); len(names) > 0 {
+				lines = append(lines, fmt.Sprintf("     %s (%s)", localName(w.To), strings.Join(names, ", ")))
+			} else {
+				lines = append(lines, "     "+localName(w.To))
+			}
+		}
+	}
+
+	return lines
+}
+
+// names returns what else the player can call way 'w', in their language
+func (w Way) names() []string {
+	var names []string
+	for _, n := range append([]string{w.Via, w.Direction}, w.Aliases...) {
+		if n != "" {
+			names = append(names, localName(n))
+		}
+	}
+
+	return names
+}
+
+// ways returns the ways out of the current room the player knows about: the
+// exits first, then the passages through features
+func ways() []Way {
+	var r []Way
+	for _, e := range curRoom.Exits {
+		w := Way{To: e}
+		for _, p := range curRoom.Passages {
+			if p.Via == "" && p.To == e {
+				if p.Direction != "" {
+					w.Direction = p.Direction
+				}
+				w.Aliases = append(w.Aliases, p.Aliases...)
+			}
+		}
+		r = append(r, w)
+	}
+
+	for _, p := range curRoom.Passages {
+		if p.Via != "" && !hidden(p) {
+			r = append(r, Way{To: p.To, Via: p.Via, Direction: p.Direction, Aliases: p.Aliases})
+		}
+	}
+
+	return r
+}
+
+// statusChanged tells the listeners how the player is doing now
+func statusChanged() {
+	emit(Event{Kind: StatusChanged, Status: Status{
+		Room:      curRoom.Name,
+		Turns:     turns,
+		Score:     score(inventory) - penalty,
+		Collected: score(inventory),
+		Needed:    len(winningItems),
+	}})
+}
+
+// inventoryChanged tells the listeners what the player is carrying now
+func inventoryChanged() {
+	var carried []string
+	for name := range inventory {
+		carried = append(carried, name)
+	}
+	sort.Strings(carried)
+
+	emit(Event{Kind: InventoryChanged, Items: carried})
+	statusChanged()
+}
+
+// exitsChanged tells the listeners the ways out of the room the player is in
+// now
+func exitsChanged() {
+	emit(Event{Kind: ExitsChanged, Exits: ways()})
+}
+
+// enterRoom puts the player in room 'r'
+func enterRoom(r *Room) {
+	curRoom = r
+	exitsChanged()
+	statusChanged()
+}
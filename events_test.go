@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// listen plays 'commands' in 'room' and returns the events the engine emitted
+func listen(room string, commands ...string) []Event {
+	var heard []Event
+	listeners = append(listeners, func(e Event) { heard = append(heard, e) })
+	defer func() { listeners = listeners[:len(listeners)-1] }()
+
+	playIn(room, commands...)
+	return heard
+}
+
+func TestTakingEmitsInventoryChanged(t *testing.T) {
+	var took, narrated bool
+	for _, e := range listen("Kitchen", "take flashlight") {
+		if e.Kind == InventoryChanged && contains(e.Items, "flashlight") {
+			took = true
+		}
+		if e.Kind == Narration && e.ID == "you-have-picked-up" {
+			narrated = true
+		}
+	}
+
+	if !took {
+		t.Error("taking the flashlight didn't say the inventory changed")
+	}
+	if !narrated {
+		t.Error("taking the flashlight didn't narrate picking it up")
+	}
+}
+
+func TestGoingEmitsExitsChanged(t *testing.T) {
+	var exits []Way
+	for _, e := range listen("Kitchen", "go to pantry") {
+		if e.Kind == ExitsChanged {
+			exits = e.Exits
+		}
+	}
+
+	if len(exits) == 0 || exits[0].To != "Kitchen" {
+		t.Errorf("going to the pantry said the exits are %v", exits)
+	}
+}
+
+func TestStateEventsHaveNoText(t *testing.T) {
+	for _, e := range listen("Kitchen", "take flashlight", "go to pantry") {
+		switch e.Kind {
+		case StatusChanged, InventoryChanged, ExitsChanged:
+			if lines := eventText(e); len(lines) > 0 {
+				t.Errorf("%s event has text %q", e.Kind, lines)
+			}
+		}
+	}
+}
+
+func TestRepeatSaysTheLastAnswerAgain(t *testing.T) {
+	said := playIn("Kitchen", "look at magnet", "repeat")
+	if n := strings.Count(said, "panda"); n != 2 {
+		t.Errorf("the magnet was described %d times, want 2:\n%s", n, said)
+	}
+}
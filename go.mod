@@ -1,3 +1,8 @@
 module github.com/ewk/adventure
 
 go 1.15
+
+require (
+	golang.org/x/crypto v0.31.0
+	golang.org/x/term v0.27.0
+)
@@ -0,0 +1,501 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// a language the game can be played in other than English, which is built in.
+// Anything a locale doesn't translate is left in English.
+type Locale struct {
+	Lang     string
+	Messages map[string]string // the game's messages, by ID
+	Words    map[string]string // what the player may type, and the English it means
+	Answers  map[string]string // how the player may answer a question, like "yes"
+	Names    map[string]string // what items and rooms are called, by their English name
+}
+
+var locale Locale // the language being played in; English if Lang is empty
+
+// the text of a room, and the items in it, in another language
+type RoomText struct {
+	Name        string // the room's English name
+	LongDesc    string
+	Description string
+	Items       map[string]*ItemText
+	Passages    []*PassageText
+}
+
+// the text of a passage out of a room in another language. The passage is the
+// one which leads to To, through Via if it has one.
+type PassageText struct {
+	To      string
+	Via     string
+	Block   string
+	First   string
+	Travel  []string // the text of each of the passage's Travel lines, in order
+	Arrives string
+}
+
+// the text of an item in another language
+type ItemText struct {
+	Description        string
+	DiscoveryStatement string
+	Eaten              string
+	Contents           map[string]*ItemText
+}
+
+// the text of an NPC in another language
+type NPCText struct {
+	Name         string // the NPC's English name
+	Description  string
+	AsleepHere   string
+	AwakeHere    string
+	Wakes        string
+	Arrives      string
+	Leaves       string
+	Follows      string
+	Summoned     string
+	SummonedHere string
+	Interactions map[string]string // the message of each interaction
+}
+
+// the text of an encounter in another language
+type EncounterText struct {
+	Name    string // the encounter's English name
+	Arrives string
+	Leaves  string
+	States  map[string]*EncounterStateText
+}
+
+// the text of one state of an encounter in another language
+type EncounterStateText struct {
+	Here      string
+	Reactions map[string][]string // the message of each reaction, in order
+}
+
+// the text of a dialogue in another language
+type DialogueText struct {
+	Name  string // who the player talks to, in English
+	Nodes map[string]*DialogueNodeText
+}
+
+// the text of one step of a conversation in another language
+type DialogueNodeText struct {
+	Text    string
+	Says    []string // the text of each of the node's lines, in order
+	Choices []string // the text of each of the node's choices, in order
+}
+
+// the text of a recipe, and what it makes, in another language
+type RecipeText struct {
+	Name    string // the recipe's English name
+	Message string
+	Ready   string
+	Output  *ItemText
+}
+
+// pickLanguage decides which language to play in: 'lang' if it's given, or
+// else the one in the LANG environment variable, like "es_ES.UTF-8"
+func pickLanguage(lang string) string {
+	if lang == "" {
+		lang = os.Getenv("LANG")
+	}
+
+	lang = strings.ToLower(regexp.MustCompile(`[_.@]`).Split(lang, 2)[0])
+	if lang == "c" || lang == "posix" || lang == "en" {
+		return ""
+	}
+
+	return lang
+}
+
+// loadLocale reads the messages and words for language 'lang' from the
+// 'locales' directory relative to the game's home directory. Languages with
+// no directory are played in English.
+func loadLocale(lang string) {
+	locale = Locale{}
+	if _, e := os.Stat("locales/" + lang); lang == "" || e != nil {
+		return
+	}
+
+	locale.Lang = lang
+	for _, f := range []string{"messages.json", "words.json"} {
+		localeJson, e := ioutil.ReadFile("locales/" + lang + "/" + f)
+		if os.IsNotExist(e) {
+			continue
+		} else if e != nil {
+			log.Fatal(e)
+		}
+
+		if e := json.Unmarshal(localeJson, &locale); e != nil {
+			panic(fmt.Sprintf("locales/%s/%s: %v", lang, f, e))
+		}
+	}
+}
+
+// translations calls 'use' with each file the locale has in its directory
+// 'kind', like "rooms" or "npcs", and stops at the first error. Locales
+// needn't translate everything, so a missing directory is fine.
+func translations(kind string, use func(file string, b []byte) error) error {
+	if locale.Lang == "" {
+		return nil
+	}
+
+	dir := "locales/" + locale.Lang + "/" + kind
+	files, e := ioutil.ReadDir(dir)
+	if os.IsNotExist(e) {
+		return nil
+	} else if e != nil {
+		return e
+	}
+
+	for _, f := range files {
+		matched, _ := regexp.MatchString(`\.json`, f.Name())
+		if matched == true {
+			b, e := ioutil.ReadFile(dir + "/" + f.Name())
+			if e != nil {
+				return e
+			}
+
+			if e := use(dir+"/"+f.Name(), b); e != nil {
+				return e
+			}
+		}
+	}
+
+	return nil
+}
+
+// localizeRooms replaces the English text of the rooms in 'rs', and the items
+// and passages in them, with any the locale has in its 'rooms' directory.
+// Each file there names a room and gives the text which is different.
+func localizeRooms(rs map[string]*Room) error {
+	return translations("rooms", func(file string, b []byte) error {
+		var t RoomText
+		if e := json.Unmarshal(b, &t); e != nil {
+			return fmt.Errorf("%s: %v", file, e)
+		}
+
+		r, ok := rs[t.Name]
+		if !ok {
+			return fmt.Errorf("%s translates a room that doesn't exist: %s", file, t.Name)
+		}
+
+		replace(&r.LongDesc, t.LongDesc)
+		replace(&r.Description, t.Description)
+		localizeItems(r.Items, t.Items)
+
+		return localizePassages(file, r, t.Passages)
+	})
+}
+
+// localizePassages replaces the English text of the passages out of room 'r'
+// with the translations in 't', from 'file'
+func localizePassages(file string, r *Room, t []*PassageText) error {
+	for _, pt := range t {
+		if pt == nil {
+			continue
+		}
+
+		var p *Exit
+		for _, q := range r.Passages {
+			if q != nil && q.To == pt.To && q.Via == pt.Via {
+				p = q
+			}
+		}
+		if p == nil {
+			return fmt.Errorf("%s translates a passage that doesn't exist: %s to %s", file, r.Name, pt.To)
+		}
+
+		replace(&p.Block, pt.Block)
+		replace(&p.First, pt.First)
+		replace(&p.Arrives, pt.Arrives)
+		for i, s := range pt.Travel {
+			if i < len(p.Travel) && p.Travel[i] != nil {
+				replace(&p.Travel[i].Text, s)
+			}
+		}
+	}
+
+	return nil
+}
+
+// localizeNPCs replaces the English text of the NPCs in 'ns' with any the
+// locale has in its 'npcs' directory
+func localizeNPCs(ns map[string]*NPC) {
+	e := translations("npcs", func(file string, b []byte) error {
+		var t NPCText
+		if e := json.Unmarshal(b, &t); e != nil {
+			return fmt.Errorf("%s: %v", file, e)
+		}
+
+		n, ok := ns[t.Name]
+		if !ok {
+			return fmt.Errorf("%s translates an NPC who doesn't exist: %s", file, t.Name)
+		}
+
+		replace(&n.Description, t.Description)
+		replace(&n.AsleepHere, t.AsleepHere)
+		replace(&n.AwakeHere, t.AwakeHere)
+		replace(&n.Wakes, t.Wakes)
+		replace(&n.Arrives, t.Arrives)
+		replace(&n.Leaves, t.Leaves)
+		replace(&n.Follows, t.Follows)
+		replace(&n.Summoned, t.Summoned)
+		replace(&n.SummonedHere, t.SummonedHere)
+		for verb, m := range t.Interactions {
+			if i, ok := n.Interactions[verb]; ok {
+				replace(&i.Message, m)
+			}
+		}
+
+		return nil
+	})
+	if e != nil {
+		panic(e)
+	}
+}
+
+// localizeEncounters replaces the English text of the encounters in 'es'
+// with any the locale has in its 'encounters' directory
+func localizeEncounters(es map[string]*Encounter) {
+	e := translations("encounters", func(file string, b []byte) error {
+		var t EncounterText
+		if e := json.Unmarshal(b, &t); e != nil {
+			return fmt.Errorf("%s: %v", file, e)
+		}
+
+		enc, ok := es[t.Name]
+		if !ok {
+			return fmt.Errorf("%s translates an encounter that doesn't exist: %s", file, t.Name)
+		}
+
+		replace(&enc.Arrives, t.Arrives)
+		replace(&enc.Leaves, t.Leaves)
+		for name, st := range t.States {
+			s, ok := enc.States[name]
+			if !ok {
+				continue
+			}
+
+			replace(&s.Here, st.Here)
+			for doing, ms := range st.Reactions {
+				for i, m := range ms {
+					if i < len(s.Reactions[doing]) {
+						replace(&s.Reactions[doing][i].Message, m)
+					}
+				}
+			}
+		}
+
+		return nil
+	})
+	if e != nil {
+		panic(e)
+	}
+}
+
+// localizeDialogues replaces the English text of the dialogues in 'ds' with
+// any the locale has in its 'dialogues' directory
+func localizeDialogues(ds map[string]*Dialogue) {
+	e := translations("dialogues", func(file string, b []byte) error {
+		var t DialogueText
+		if e := json.Unmarshal(b, &t); e != nil {
+			return fmt.Errorf("%s: %v", file, e)
+		}
+
+		d, ok := ds[t.Name]
+		if !ok {
+			return fmt.Errorf("%s translates a dialogue that doesn't exist: %s", file, t.Name)
+		}
+
+		for name, nt := range t.Nodes {
+			n, ok := d.Nodes[name]
+			if !ok {
+				continue
+			}
+
+			replace(&n.Text, nt.Text)
+			for i, s := range nt.Says {
+				if i < len(n.Says) {
+					replace(&n.Says[i].Text, s)
+				}
+			}
+			for i, s := range nt.Choices {
+				if i < len(n.Choices) {
+					replace(&n.Choices[i].Text, s)
+				}
+			}
+		}
+
+		return nil
+	})
+	if e != nil {
+		panic(e)
+	}
+}
+
+// localizeRecipes replaces the English text of the recipes in 'rs', and of
+// what they make, with any the locale has in its 'recipes' directory
+func localizeRecipes(rs map[string]*Recipe) {
+	e := translations("recipes", func(file string, b []byte) error {
+		var t RecipeText
+		if e := json.Unmarshal(b, &t); e != nil {
+			return fmt.Errorf("%s: %v", file, e)
+		}
+
+		r, ok := rs[t.Name]
+		if !ok {
+			return fmt.Errorf("%s translates a recipe that doesn't exist: %s", file, t.Name)
+		}
+
+		replace(&r.Message, t.Message)
+		replace(&r.Ready, t.Ready)
+		if t.Output != nil && r.Output != nil {
+			localizeItems(map[string]*Item{r.Output.Name: r.Output}, map[string]*ItemText{r.Output.Name: t.Output})
+		}
+
+		return nil
+	})
+	if e != nil {
+		panic(e)
+	}
+}
+
+// localizeItems replaces the English text of 'items' with the translations
+// in 't'
+func localizeItems(items map[string]*Item, t map[string]*ItemText) {
+	for name, it := range t {
+		item, ok := items[name]
+		if !ok || item == nil || it == nil {
+			continue
+		}
+
+		replace(&item.Description, it.Description)
+		replace(&item.DiscoveryStatement, it.DiscoveryStatement)
+		replace(&item.Eaten, it.Eaten)
+		localizeItems(item.Contents, it.Contents)
+	}
+}
+
+// replace sets 's' to 't', unless there's no 't'
+func replace(s *string, t string) {
+	if t != "" {
+		*s = t
+	}
+}
+
+// text returns the message 'id' in the player's language, or 'english' if it
+// hasn't been translated, filled in with 'args' like fmt.Sprintf. Any of the
+// 'args' which name an item or a room are given their local names.
+func text(id string, english string, args ...interface{}) string {
+	format := english
+	if t, ok := locale.Messages[id]; ok {
+		format = t
+	}
+
+	if len(args) == 0 {
+		return format
+	}
+
+	for i, arg := range args {
+		if name, ok := arg.(string); ok {
+			args[i] = localName(name)
+		}
+	}
+
+	return fmt.Sprintf(format, args...)
+}
+
+// say says the message 'id' in the player's language, on a line of its own
+func say(id string, english string, args ...interface{}) {
+	emit(Event{Kind: Narration, ID: id, Text: phrase(id, english, args...)})
+}
+
+// phrase returns the message 'id' in the player's language, as it should be
+// said. A screen reader needs every sentence to start with a capital letter.
+func phrase(id string, english string, args ...interface{}) string {
+	if accessible {
+		return sentence(text(id, english, args...))
+	}
+
+	return text(id, english, args...)
+}
+
+// localName is what the item or room called 'name' is called in the player's
+// language
+func localName(name string) string {
+	if n, ok := locale.Names[name]; ok {
+		return n
+	}
+
+	return name
+}
+
+// translateAnswer turns the player's answer to a question in their language,
+// like "sí", into the 'y' or 'n' the game asks for. Answers are only
+// translated when the game has asked something, so "no" isn't taken for
+// "n", which means north.
+func translateAnswer(answer string) string {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if en, ok := locale.Answers[answer]; ok {
+		return en
+	}
+
+	return answer
+}
+
+// translateInput turns what the player typed in their language into the
+// English the parser understands. Words it doesn't know are left alone, so
+// English always works too.
+func translateInput(action string) string {
+	if locale.Lang == "" {
+		return action
+	}
+
+	english := make(map[string]string)
+	longest := 1
+	for en, local := range locale.Names {
+		english[strings.ToLower(local)] = strings.ToLower(en)
+	}
+	for local, en := range locale.Words {
+		english[local] = en
+	}
+	for local := range english {
+		if n := len(strings.Fields(local)); n > longest {
+			longest = n
+		}
+	}
+
+	// replace the longest phrases first, so "cepillo de dientes" is one thing
+	// and not three
+	words := strings.Fields(action)
+	var t []string
+	for i := 0; i < len(words); {
+		n := longest
+		if n > len(words)-i {
+			n = len(words) - i
+		}
+
+		for ; n > 0; n-- {
+			if en, ok := english[strings.Join(words[i:i+n], " ")]; ok {
+				t = append(t, en)
+				break
+			}
+		}
+
+		if n == 0 {
+			t = append(t, words[i])
+			n = 1
+		}
+		i += n
+	}
+
+	return strings.Join(t, " ")
+}
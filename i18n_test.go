@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestTranslateInput(t *testing.T) {
+	loadLocale("es")
+	defer loadLocale("")
+
+	for typed, want := range map[string]string{
+		"coger vela de cumpleaños": "take birthday candle",
+		"ir a despensa":            "go to pantry",
+		"salir":                    "out",
+		"abandonar":                "quit",
+		"no":                       "no",
+		"take magnet":              "take magnet",
+	} {
+		if got := translateInput(typed); got != want {
+			t.Errorf("translateInput(%q) = %q, want %q", typed, got, want)
+		}
+	}
+}
+
+func TestTranslateAnswer(t *testing.T) {
+	loadLocale("es")
+	defer loadLocale("")
+
+	for typed, want := range map[string]string{"Sí": "y", "si ": "y", "no": "n", "y": "y"} {
+		if got := translateAnswer(typed); got != want {
+			t.Errorf("translateAnswer(%q) = %q, want %q", typed, got, want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"sort"
+)
+
+// how well lit a room can be. Rooms are bright unless they say otherwise.
+const (
+	Dim  = "dim"  // hidden things can't be found without a light
+	Dark = "dark" // nothing can be seen without a light
+)
+
+const darkMessage = "It's pitch dark in here. You can't see a thing!\nIf only you had some light."
+
+// the verbs which need the player to see what they're doing
+var needsLight = map[string]bool{
+	"take": true, "grab": true, "pull": true, "climb": true, "shrink": true,
+	"grow": true, "enlarge": true, "open": true, "close": true, "shut": true,
+	"lock": true, "unlock": true, "cut": true, "enter": true, "use": true,
+}
+
+// commands which tell the player about the game, rather than do anything in
+// it, and so don't give candles a chance to burn down
+var takesNoTime = map[string]bool{
+	"help": true, "inventory": true, "mystuff": true, "exits": true,
+	"map": true, "verbose": true, "brief": true, "superbrief": true,
+	"savegame": true, "loadgame": true,
+}
+
+// lit reports whether the player has a light with them, or there's one in the
+// room, even inside something which is open
+func lit() bool {
+	return glowing(inventory) || glowing(curRoom.Items)
+}
+
+// glowing reports whether any of 'items', or anything inside them which isn't
+// closed away, is lit
+func glowing(items map[string]*Item) bool {
+	for _, item := range items {
+		if item.Lit || !item.Closed && glowing(item.Contents) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// canSee reports whether the current room is light enough to see everything
+// in it
+func canSee() bool {
+	return curRoom.Light == "" || lit()
+}
+
+// visible reports whether 'item' in the current room can be made out
+func visible(item *Item) bool {
+	return item.Discovered && (curRoom.Light != Dark || lit())
+}
+
+// listRoomItems lists the things the player can see in the current room
+func listRoomItems() {
+	var names []string
+	for _, item := range curRoom.Items {
+		if visible(item) {
+			names = append(names, item.Name)
+		}
+	}
+	sort.Strings(names)
+
+	emit(Event{Kind: ItemsSeen, Items: names, Dim: curRoom.Light == Dim && !canSee()})
+}
+
+// flame returns something in the current room or the player's inventory
+// which could light 'item', or nil if there isn't anything
+func flame(item *Item) *Item {
+	for _, items := range []map[string]*Item{curRoom.Items, inventory} {
+		for _, val := range items {
+			if val != item && (val.Flame && val.Discovered || val.Lit) {
+				return val
+			}
+		}
+	}
+
+	return nil
+}
+
+// lightItem lights 'item', if it will burn and there's a flame to light it
+// from
+func lightItem(item string) {
+	val, ok := inventory[item]
+	if !ok {
+		say("is-not-in-your", "%s is not in your backpack.", item)
+		return
+	}
+
+	if !val.IsLight {
+		say("you-cant-light-the", "You can't light the %s.", item)
+		return
+	}
+
+	if val.Lit {
+		say("the-is-already-lit", "The %s is already lit.", item)
+		return
+	}
+
+	if val.Everlasting {
+		val.Lit = true
+		say("you-switch-on-the", "You switch on the %s.", item)
+	} else if val.Burns == 0 {
+		say("the-has-burned-all", "The %s has burned all the way down. It won't light again.", item)
+		return
+	} else if f := flame(val); f == nil {
+		say("you-need-a-flame", "You need a flame to light the %s. Is there a fire anywhere?", item)
+		return
+	} else {
+		val.Lit = true
+		say("you-light-the-from", "You light the %s from the %s. It flickers and glows.", item, f.Name)
+	}
+
+	if curRoom.Light != "" {
+		lookAtRoom()
+	}
+}
+
+// putOut puts out the lit 'item', saving what's left of it for later
+func putOut(item string) {
+	val, ok := inventory[item]
+	if !ok || !val.Lit {
+		say("you-dont-have-a", "You don't have a lit %s.", item)
+		return
+	}
+
+	val.Lit = false
+	if val.Everlasting {
+		say("you-switch-off-the", "You switch off the %s.", item)
+	} else {
+		say("you-blow-out-the", "You blow out the %s.", item)
+	}
+	if curRoom.Light == Dark && !canSee() {
+		say("everything-goes-dark", "Everything goes dark.")
+	}
+}
+
+// burnLights burns every lit item down by a turn, even inside something else,
+// putting out those which have burned all the way down
+func burnLights() {
+	var burn func(items map[string]*Item, carried bool)
+	burn = func(items map[string]*Item, carried bool) {
+		for name, item := range items {
+			burn(item.Contents, carried)
+			if !item.Lit || item.Everlasting {
+				continue
+			}
+
+			item.Burns--
+			switch {
+			case item.Burns == 0:
+				item.Lit = false
+				if carried {
+					say("your-sputters-and-goes", "\nYour %s sputters and goes out.", name)
+				}
+			case item.Burns == 5 && carried:
+				say("your-is-burning-low", "\nYour %s is burning low.", name)
+			}
+		}
+	}
+
+	burn(inventory, true)
+	for _, r := range rooms {
+		burn(r.Items, false)
+	}
+}
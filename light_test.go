@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestLightInsideSomethingOpenLightsTheRoom(t *testing.T) {
+	playIn("Basement Lab")
+	candle := copyItem(catalog["candle"])
+	candle.Lit = true
+	inventory["backpack"].Contents = map[string]*Item{"candle": candle}
+
+	inventory["backpack"].Closed = false
+	if !lit() {
+		t.Error("the candle in the open backpack doesn't light the room")
+	}
+
+	inventory["backpack"].Closed = true
+	if lit() {
+		t.Error("the candle in the closed backpack lights the room")
+	}
+
+	burns := candle.Burns
+	burnLights()
+	if candle.Burns != burns-1 {
+		t.Errorf("the candle in the backpack burned from %d to %d", burns, candle.Burns)
+	}
+}
+
+func TestFlashlightNeverRunsOut(t *testing.T) {
+	playIn("Kitchen", "take flashlight", "light flashlight")
+	flashlight := inventory["flashlight"]
+	if flashlight == nil || !flashlight.Lit {
+		t.Fatal("the flashlight didn't switch on without a flame")
+	}
+
+	for i := 0; i < 1000; i++ {
+		burnLights()
+	}
+	if !flashlight.Lit {
+		t.Error("the flashlight ran out")
+	}
+}
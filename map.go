@@ -0,0 +1,488 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// where a room is drawn on the map. X grows to the east and Y to the south.
+type Layout struct {
+	Floor int // 0 is the ground floor; the basement is -1
+	X     int
+	Y     int
+}
+
+// how a step in each direction moves across the map
+var compass = map[string]Layout{
+	"north": {Y: -1},
+	"south": {Y: 1},
+	"east":  {X: 1},
+	"west":  {X: -1},
+	"up":    {Floor: 1},
+	"down":  {Floor: -1},
+}
+
+var floorNames = map[int]string{-1: "the basement", 0: "the ground floor", 1: "upstairs", 2: "the attic"}
+
+// floorName names floor 'f' for a map's title
+func floorName(f int) string {
+	if name, ok := floorNames[f]; ok {
+		return text(fmt.Sprintf("floor%+d", f), name)
+	}
+
+	return text("floor", "floor %d", f)
+}
+
+// mapPassages lists every way out of room 'r', including exits which have no
+// passage described
+func mapPassages(r *Room) []*Exit {
+	ps := append([]*Exit{}, r.Passages...)
+	for _, e := range r.Exits {
+		found := false
+		for _, p := range r.Passages {
+			found = found || p.Via == "" && p.To == e
+		}
+
+		if !found {
+			ps = append(ps, &Exit{To: e})
+		}
+	}
+
+	return ps
+}
+
+// placeRooms decides where each of 'rs' is drawn. Rooms with a Layout go
+// where it says; the rest go beside a neighbour, in the direction of the
+// exit between them, or in the nearest free spot.
+func placeRooms(rs map[string]*Room) map[string]Layout {
+	var names []string
+	for name := range rs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pos := make(map[string]Layout)
+	taken := make(map[Layout]bool)
+	put := func(name string, l Layout) {
+		// take the nearest free spot on the same floor
+		for r := 0; taken[l]; r++ {
+			for dy := -r; dy <= r && taken[l]; dy++ {
+				for dx := -r; dx <= r && taken[l]; dx++ {
+					if c := (Layout{l.Floor, l.X + dx, l.Y + dy}); !taken[c] {
+						l = c
+					}
+				}
+			}
+		}
+		pos[name] = l
+		taken[l] = true
+	}
+
+	for _, name := range names {
+		if l := rs[name].Layout; l != nil {
+			put(name, *l)
+		}
+	}
+
+	for placed := true; placed; {
+		placed = false
+		for _, name := range names {
+			if _, ok := pos[name]; ok {
+				continue
+			}
+
+			for _, other := range names {
+				from, ok := pos[other]
+				if !ok {
+					continue
+				}
+
+				for _, p := range mapPassages(rs[other]) {
+					if p.To == name {
+						d := compass[p.Direction]
+						put(name, Layout{from.Floor + d.Floor, from.X + d.X, from.Y + d.Y})
+						placed = true
+						break
+					}
+				}
+
+				if placed {
+					break
+				}
+			}
+		}
+	}
+
+	// rooms which can't be reached from any room with a place go in a row
+	// of their own
+	for _, name := range names {
+		if _, ok := pos[name]; !ok {
+			put(name, Layout{Y: 100})
+		}
+	}
+
+	return pos
+}
+
+// drawMap draws the floor the player is on, showing the rooms they've been to
+// and the ones they know the way to
+func drawMap() {
+	pos := placeRooms(rooms)
+	floor := pos[curRoom.Name].Floor
+
+	shown := make(map[string]bool)
+	linked := make(map[[2]string]bool)
+	up := make(map[string]bool)
+	down := make(map[string]bool)
+
+	for name, r := range rooms {
+		if !r.Visited && r != curRoom || pos[name].Floor != floor {
+			continue
+		}
+		shown[name] = true
+
+		for _, p := range mapPassages(r) {
+			if item, ok := r.Items[p.Via]; ok && !item.Discovered {
+				continue
+			}
+
+			switch to := pos[p.To]; {
+			case p.Direction == "up" || to.Floor > floor:
+				up[name] = true
+			case p.Direction == "down" || to.Floor < floor:
+				down[name] = true
+			default:
+				shown[p.To] = true
+				linked[[2]string{name, p.To}] = true
+				linked[[2]string{p.To, name}] = true
+			}
+		}
+	}
+
+	if accessible {
+		sayFloor(floor, shown, linked, up, down)
+		return
+	}
+
+	at := make(map[Layout]string)
+	label := make(map[string]string)
+	first := true
+	var min, max Layout
+	width := 0
+	for name := range shown {
+		l := pos[name]
+		at[l] = name
+
+		if first || l.X < min.X {
+			min.X = l.X
+		}
+		if first || l.Y < min.Y {
+			min.Y = l.Y
+		}
+		if first || l.X > max.X {
+			max.X = l.X
+		}
+		if first || l.Y > max.Y {
+			max.Y = l.Y
+		}
+		first = false
+
+		switch {
+		case rooms[name] == curRoom:
+			label[name] = "*" + localName(name) + "*"
+		case !rooms[name].Visited:
+			label[name] = "(" + localName(name) + ")"
+		default:
+			label[name] = localName(name)
+		}
+
+		if up[name] {
+			label[name] += " ^"
+		}
+		if down[name] {
+			label[name] += " v"
+		}
+
+		if utf8.RuneCountInString(label[name]) > width {
+			width = utf8.RuneCountInString(label[name])
+		}
+	}
+
+	centre := func(s string) string {
+		n := utf8.RuneCountInString(s)
+		left := (width - n) / 2
+		return strings.Repeat(" ", left) + s + strings.Repeat(" ", width-n-left)
+	}
+
+	say("map-of", "Map of %s:\n", floorName(floor))
+	for y := min.Y; y <= max.Y; y++ {
+		var cells, links []string
+		for x := min.X; x <= max.X; x++ {
+			here := at[Layout{floor, x, y}]
+			cells = append(cells, centre(label[here]))
+
+			if linked[[2]string{here, at[Layout{floor, x, y + 1}]}] {
+				links = append(links, centre("|"))
+			} else {
+				links = append(links, centre(""))
+			}
+		}
+
+		// rooms side by side are joined by a line from one name to the next
+		var row strings.Builder
+		for i := range cells {
+			if i == 0 {
+				row.WriteString(cells[i])
+				continue
+			}
+
+			if linked[[2]string{at[Layout{floor, min.X + i - 1, y}], at[Layout{floor, min.X + i, y}]}] {
+				joined := strings.TrimRight(row.String(), " ")
+				row.Reset()
+				row.WriteString(joined)
+				row.WriteString(strings.Repeat("-", width-utf8.RuneCountInString(strings.TrimRight(cells[i-1], " "))+3))
+				row.WriteString(strings.Replace(cells[i], " ", "-", len(cells[i])-len(strings.TrimLeft(cells[i], " "))))
+			} else {
+				row.WriteString("   ")
+				row.WriteString(cells[i])
+			}
+		}
+
+		narrate(strings.TrimRight(row.String(), " "))
+		if y < max.Y {
+			narrate(strings.TrimRight(strings.Join(links, "   "), " "))
+		}
+	}
+
+	say("you-are-here-not", "\n*you are here*  (not been there yet)  ^ v a way up or down")
+}
+
+// edgeLabel describes passage 'p' for a map of the whole house: which way it
+// goes and what it takes to get through
+func edgeLabel(p *Exit) string {
+	var words []string
+	if p.Via != "" {
+		words = append(words, p.Via)
+	}
+	if p.Direction != "" {
+		words = append(words, p.Direction)
+	}
+
+	if p.If != nil {
+		for _, item := range p.If.Has {
+			words = append(words, "needs "+item)
+		}
+		for _, item := range p.If.Lacks {
+			words = append(words, "without "+item)
+		}
+	}
+
+	if p.Climbed {
+		words = append(words, "climb up first")
+	}
+	if p.Carrier != "" {
+		words = append(words, "carried by the "+p.Carrier)
+	}
+
+	return strings.Join(words, ", ")
+}
+
+// an exit from one room to another, for drawing
+type mapEdge struct {
+	From  string
+	To    string
+	Label string
+}
+
+// mapEdges lists every way between the rooms in 'rs', in order
+func mapEdges(rs map[string]*Room) []mapEdge {
+	var edges []mapEdge
+	for name, r := range rs {
+		for _, p := range mapPassages(r) {
+			edges = append(edges, mapEdge{name, p.To, edgeLabel(p)})
+		}
+	}
+
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+
+	return edges
+}
+
+// floors lists the floors of the rooms placed in 'pos', from the top down,
+// with the rooms on each in order
+func floors(pos map[string]Layout) ([]int, map[int][]string) {
+	on := make(map[int][]string)
+	for name, l := range pos {
+		on[l.Floor] = append(on[l.Floor], name)
+	}
+
+	var fs []int
+	for f := range on {
+		fs = append(fs, f)
+		sort.Strings(on[f])
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(fs)))
+
+	return fs, on
+}
+
+// writeDot writes the rooms in 'rs' as a Graphviz graph, with a cluster for
+// each floor
+func writeDot(w io.Writer, rs map[string]*Room) {
+	pos := placeRooms(rs)
+	fs, on := floors(pos)
+
+	fmt.Fprintln(w, "digraph house {")
+	fmt.Fprintln(w, "\tnode [shape=box];")
+	for _, f := range fs {
+		fmt.Fprintf(w, "\tsubgraph \"cluster_%d\" {\n", f)
+		fmt.Fprintf(w, "\t\tlabel=%q;\n", strings.Title(floorName(f)))
+		for _, name := range on[f] {
+			fmt.Fprintf(w, "\t\t%q;\n", name)
+		}
+		fmt.Fprintln(w, "\t}")
+	}
+
+	for _, e := range mapEdges(rs) {
+		fmt.Fprintf(w, "\t%q -> %q [label=%q];\n", e.From, e.To, e.Label)
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// writeMermaid writes the rooms in 'rs' as a Mermaid flowchart, with a
+// subgraph for each floor
+func writeMermaid(w io.Writer, rs map[string]*Room) {
+	pos := placeRooms(rs)
+	fs, on := floors(pos)
+	id := func(name string) string {
+		return regexp.MustCompile(`\W`).ReplaceAllString(name, "_")
+	}
+
+	fmt.Fprintln(w, "flowchart TD")
+	for i, f := range fs {
+		fmt.Fprintf(w, "\tsubgraph floor%d [\"%s\"]\n", i, strings.Title(floorName(f)))
+		for _, name := range on[f] {
+			fmt.Fprintf(w, "\t\t%s[\"%s\"]\n", id(name), name)
+		}
+		fmt.Fprintln(w, "\tend")
+	}
+
+	for _, e := range mapEdges(rs) {
+		if e.Label != "" {
+			fmt.Fprintf(w, "\t%s -->|\"%s\"| %s\n", id(e.From), e.Label, id(e.To))
+		} else {
+			fmt.Fprintf(w, "\t%s --> %s\n", id(e.From), id(e.To))
+		}
+	}
+}
+
+// writeSVG draws the rooms in 'rs' as a picture, one floor above another.
+// Ways between rooms on the same floor are lines; ways up and down are
+// listed in the room they leave from.
+func writeSVG(w io.Writer, rs map[string]*Room) {
+	const cellW, cellH, boxW, boxH, title = 190, 90, 160, 60, 30
+
+	pos := placeRooms(rs)
+	fs, on := floors(pos)
+
+	var min, max Layout
+	first := true
+	for _, l := range pos {
+		if first || l.X < min.X {
+			min.X = l.X
+		}
+		if first || l.Y < min.Y {
+			min.Y = l.Y
+		}
+		if first || l.X > max.X {
+			max.X = l.X
+		}
+		if first || l.Y > max.Y {
+			max.Y = l.Y
+		}
+		first = false
+	}
+
+	panel := (max.Y-min.Y+1)*cellH + title
+	top := make(map[int]int)
+	for i, f := range fs {
+		top[f] = i * panel
+	}
+
+	centre := func(name string) (int, int) {
+		l := pos[name]
+		return (l.X-min.X)*cellW + cellW/2, top[l.Floor] + title + (l.Y-min.Y)*cellH + cellH/2
+	}
+
+	fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" font-family=\"sans-serif\" font-size=\"12\">\n",
+		(max.X-min.X+1)*cellW, len(fs)*panel)
+	fmt.Fprintln(w, "<rect width=\"100%\" height=\"100%\" fill=\"white\"/>")
+
+	for _, f := range fs {
+		fmt.Fprintf(w, "<text x=\"8\" y=\"%d\" font-size=\"16\" font-weight=\"bold\">%s</text>\n",
+			top[f]+20, html.EscapeString(strings.Title(floorName(f))))
+	}
+
+	vertical := make(map[string][]string)
+	for _, e := range mapEdges(rs) {
+		if pos[e.From].Floor != pos[e.To].Floor {
+			vertical[e.From] = append(vertical[e.From], e.To+" ("+e.Label+")")
+			continue
+		}
+
+		x1, y1 := centre(e.From)
+		x2, y2 := centre(e.To)
+		fmt.Fprintf(w, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"gray\"/>\n", x1, y1, x2, y2)
+	}
+
+	for _, f := range fs {
+		for _, name := range on[f] {
+			x, y := centre(name)
+			fmt.Fprintf(w, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"ivory\" stroke=\"black\"/>\n",
+				x-boxW/2, y-boxH/2, boxW, boxH)
+			fmt.Fprintf(w, "<text x=\"%d\" y=\"%d\" text-anchor=\"middle\" font-weight=\"bold\">%s</text>\n",
+				x, y-boxH/2+16, html.EscapeString(name))
+
+			for i, v := range vertical[name] {
+				fmt.Fprintf(w, "<text x=\"%d\" y=\"%d\" text-anchor=\"middle\" font-size=\"9\">%s</text>\n",
+					x, y-boxH/2+30+i*11, html.EscapeString(v))
+			}
+		}
+	}
+
+	fmt.Fprintln(w, "</svg>")
+}
+
+// mapCommand renders the whole house from the room definitions, for
+// 'adventure map'
+func mapCommand(args []string) {
+	fs := flag.NewFlagSet("map", flag.ExitOnError)
+	format := fs.String("format", "dot", "draw the map as 'dot', 'svg' or 'mermaid'")
+	fs.Parse(args)
+
+	loadRooms()
+
+	switch *format {
+	case "dot":
+		writeDot(os.Stdout, rooms)
+	case "svg":
+		writeSVG(os.Stdout, rooms)
+	case "mermaid":
+		writeMermaid(os.Stdout, rooms)
+	default:
+		log.Fatalf("unknown map format '%s'", *format)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// how the markup in the game's text is shown. The text marks the words a
+// player can use: {verb:take} the {item:software} and go to the {exit:yard}.
+const (
+	PlainText = iota // in capitals, like TAKE the SOFTWARE
+	ColorText        // in colour and bold, for terminals
+	HTMLText         // as links which send a command, for web pages
+)
+
+var markup = regexp.MustCompile(`\{(verb|item|exit):([^{}]*)\}`)
+
+// the ANSI colours for each kind of markup
+var colors = map[string]string{
+	"verb": "\x1b[1m",
+	"item": "\x1b[1;33m",
+	"exit": "\x1b[1;36m",
+}
+
+// render shows the markup in 's' in 'style'
+func render(s string, style int) string {
+	if style == HTMLText {
+		var b strings.Builder
+		last := 0
+		for _, m := range markup.FindAllStringSubmatchIndex(s, -1) {
+			b.WriteString(html.EscapeString(s[last:m[0]]))
+			kind, words := s[m[2]:m[3]], s[m[4]:m[5]]
+			b.WriteString(`<a href="#" class="` + kind + `" data-command="` + html.EscapeString(command(kind, words)) + `">`)
+			b.WriteString(html.EscapeString(words) + "</a>")
+			last = m[1]
+		}
+		b.WriteString(html.EscapeString(s[last:]))
+
+		return b.String()
+	}
+
+	return markup.ReplaceAllStringFunc(s, func(m string) string {
+		parts := markup.FindStringSubmatch(m)
+		if style == ColorText {
+			return colors[parts[1]] + parts[2] + "\x1b[0m"
+		}
+
+		return strings.ToUpper(parts[2])
+	})
+}
+
+// renderLines shows the markup in each of 'lines' in 'style'
+func renderLines(lines []string, style int) []string {
+	r := make([]string, len(lines))
+	for i, l := range lines {
+		r[i] = render(l, style)
+	}
+
+	return r
+}
+
+// command is what a player would type to act on the marked up 'words'
+func command(kind string, words string) string {
+	switch kind {
+	case "item":
+		return "look at " + words
+	case "exit":
+		return "go " + words
+	}
+
+	return words
+}
+
+// terminalStyle is how markup should be shown on standard output: in colour
+// on a terminal, unless the NO_COLOR environment variable is set
+func terminalStyle() int {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return PlainText
+	}
+
+	return ColorText
+}
+
+// an io.Writer which renders the markup in what's written to it
+type styler struct {
+	w     io.Writer
+	style int
+}
+
+func (s *styler) Write(p []byte) (int, error) {
+	if _, e := io.WriteString(s.w, render(string(p), s.style)); e != nil {
+		return 0, e
+	}
+
+	return len(p), nil
+}
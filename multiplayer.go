@@ -0,0 +1,286 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// how other players see someone arrive by a route which isn't a passage,
+// keyed by "<from room>><to room>"
+var arrivals = map[string]string{
+	"Yard>Large Bedroom": "%s tumbles out of the chimney, covered in soot.",
+}
+
+// a house shared by several shrunken players. Each player meets the house's
+// encounters on their own, from the copy in their game.
+type House struct {
+	rooms   map[string]*Room
+	npcs    map[string]*NPC
+	players map[string]*Player
+	winner  string
+}
+
+// a player in the shared house
+type Player struct {
+	Name   string
+	s      *Session
+	notify chan string // things the player sees other players do
+}
+
+// the house shared by everyone playing over SSH with -shared; guarded by
+// engineMu
+var house *House
+
+// joinHouse adds the player 'name' to the shared house, starting a new house
+// if nobody is playing. Messages for the player are written to 'w' until they
+// leave.
+func joinHouse(name string, w io.Writer) (*Player, error) {
+	engineMu.Lock()
+	defer engineMu.Unlock()
+
+	if house == nil || len(house.players) == 0 {
+		house = &House{
+			rooms:   copyRooms(world),
+			npcs:    copyNPCs(cast),
+			players: make(map[string]*Player),
+		}
+	}
+
+	if _, ok := house.players[name]; ok {
+		return nil, fmt.Errorf("%s is already playing", name)
+	}
+
+	g := newGame()
+	g.Rooms = house.rooms
+	g.NPCs = house.npcs
+
+	p := &Player{
+		Name:   name,
+		s:      &Session{ID: name, Game: g, LastUsed: time.Now()},
+		notify: make(chan string, 32),
+	}
+	house.players[name] = p
+
+	go func() {
+		for m := range p.notify {
+			fmt.Fprintln(w, m)
+		}
+	}()
+
+	house.tell(p.s.Game.CurRoom, p, text("appears-in-a-flash", "%s appears in a flash of purple light.", name))
+
+	return p, nil
+}
+
+// leaveHouse removes player 'p' from the shared house. Anything they were
+// carrying is left behind for the other players, including what's in their
+// backpack, but not their shrink ray or the backpack itself.
+func leaveHouse(p *Player) {
+	engineMu.Lock()
+	defer engineMu.Unlock()
+
+	room := house.rooms[p.s.Game.CurRoom]
+	for name, item := range p.s.Game.Inventory {
+		if name == "shrink ray" {
+			continue
+		}
+
+		if item.Capacity == 0 {
+			room.Items[name] = item
+			continue
+		}
+
+		for inside, val := range item.Contents {
+			room.Items[inside] = val
+		}
+	}
+
+	delete(house.players, p.Name)
+	close(p.notify)
+
+	house.tell(room.Name, p, text("vanishes-leaving-their-things", "%s vanishes, leaving their things behind.", p.Name))
+}
+
+// tell sends 'msg' to every player in 'room' except 'from'. Callers must
+// hold engineMu.
+func (h *House) tell(room string, from *Player, msg string) {
+	for _, p := range h.players {
+		if p != from && p.s.Game.CurRoom == room {
+			p.send(msg)
+		}
+	}
+}
+
+// tellAll sends 'msg' to every player in the house. Callers must hold
+// engineMu.
+func (h *House) tellAll(msg string) {
+	for _, p := range h.players {
+		p.send(msg)
+	}
+}
+
+// send shows 'msg' to player 'p'. Callers must hold engineMu.
+func (p *Player) send(msg string) {
+	select {
+	case p.notify <- msg:
+	default: // the player isn't keeping up, so they miss it
+	}
+}
+
+// playSharedTurn runs 'action' for player 'p', letting the other players
+// see them come and go. It returns the text the player sees.
+func playSharedTurn(p *Player, action string) []string {
+	engineMu.Lock()
+	from := p.s.Game.CurRoom
+	engineMu.Unlock()
+
+	lines := playTurn(p.s, action).Output
+
+	engineMu.Lock()
+	defer engineMu.Unlock()
+
+	to := p.s.Game.CurRoom
+	if to != from {
+		house.tell(from, p, text("leaves-for-the", "%s leaves for the %s.", p.Name, strings.ToLower(localName(to))))
+
+		if arrive := arrival(house.rooms[from], to); arrive != "" {
+			house.tell(to, p, fmt.Sprintf(arrive, p.Name))
+		} else if arrive, ok := arrivals[from+">"+to]; ok {
+			house.tell(to, p, fmt.Sprintf(arrive, p.Name))
+		} else if to == "Staircase" && from != "Upstairs Hallway" {
+			house.tell(to, p, text("arrives-riding-on-the", "%s arrives riding on the dog.", p.Name))
+		} else {
+			house.tell(to, p, text("walks-in-from-the", "%s walks in from the %s.", p.Name, strings.ToLower(localName(from))))
+		}
+	}
+
+	if p.s.GameOver && score(p.s.Game.Inventory) == len(winningItems) {
+		house.winner = p.Name
+		house.tellAll(text("fixed-the-shrink-ray", "\n*** %s fixed the shrink ray and is back to normal size! ***", p.Name))
+	}
+
+	return lines
+}
+
+// arrival returns how other players see someone arrive in room 'to' through
+// one of the passages out of room 'r'
+func arrival(r *Room, to string) string {
+	for _, p := range r.Passages {
+		if p.To == to && p.Arrives != "" {
+			return p.Arrives
+		}
+	}
+
+	return ""
+}
+
+// speak lets everyone in the same room as player 'p' hear them
+func speak(p *Player, words string) string {
+	engineMu.Lock()
+	defer engineMu.Unlock()
+
+	house.tell(p.s.Game.CurRoom, p, text("says", "%s says \"%s\"", p.Name, words))
+
+	return text("you-say", "You say \"%s\"", words)
+}
+
+// give hands 'item' from player 'p' to the player 'to', if they are in the
+// same room
+func give(p *Player, item string, to string) string {
+	engineMu.Lock()
+	defer engineMu.Unlock()
+
+	other, ok := house.players[to]
+	if !ok || other.s.Game.CurRoom != p.s.Game.CurRoom {
+		return text("theres-nobody-called-here-2", "There's nobody called %s here.", to)
+	}
+
+	val, ok := p.s.Game.Inventory[item]
+	if !ok {
+		return text("is-not-in-your", "%s is not in your backpack.", item)
+	}
+
+	if item == "shrink ray" {
+		return text("your-shrink-ray-is", "Your shrink ray is the only thing that can get you back to normal. Keep it!")
+	}
+
+	if val.Capacity > 0 {
+		return text("you-need-your-to", "You need your %s to carry your things.", item)
+	}
+
+	if carrying(other.s.Game.Inventory)+val.weight() > capacity(other.s.Game.Inventory) {
+		return text("cant-carry-any-more", "%s can't carry any more.", to)
+	}
+
+	delete(p.s.Game.Inventory, item)
+	other.s.Game.Inventory[item] = val
+
+	other.send(text("gives-you-the", "%s gives you the %s.", p.Name, item))
+	house.tell(p.s.Game.CurRoom, p, text("gives-the-to", "%s gives the %s to %s.", p.Name, item, to))
+
+	return text("you-give-the-to", "You give the %s to %s.", item, to)
+}
+
+// who lists everyone in the house and where they are
+func who() []string {
+	engineMu.Lock()
+	defer engineMu.Unlock()
+
+	var names []string
+	for name := range house.players {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		p := house.players[name]
+		lines = append(lines, text("is-in-the-carrying", "%s is in the %s, carrying %d of the %d things on the list.",
+			name, strings.ToLower(p.s.Game.CurRoom), score(p.s.Game.Inventory), len(winningItems)))
+	}
+
+	return lines
+}
+
+// houseWinner returns the name of the player who won the game, if any
+func houseWinner() string {
+	engineMu.Lock()
+	defer engineMu.Unlock()
+
+	return house.winner
+}
+
+// sharedCommand carries out the commands only found in the shared house. It
+// returns false if 'action' is an ordinary command.
+func sharedCommand(p *Player, action string) ([]string, bool) {
+	f := strings.Fields(action)
+	if len(f) == 0 {
+		return nil, false
+	}
+
+	switch strings.ToLower(f[0]) {
+	case "say":
+		if len(f) < 2 {
+			return []string{text("say-what", "Say what?")}, true
+		}
+		return []string{speak(p, strings.Join(f[1:], " "))}, true
+	case "give":
+		// give <item> to <player>
+		words := strings.Fields(strings.ToLower(action))
+		for i := len(words) - 2; i > 1; i-- {
+			if words[i] == "to" {
+				return []string{give(p, strings.Join(words[1:i], " "), words[i+1])}, true
+			}
+		}
+		return []string{text("give-what-to-whom", "Give what to whom? Try: give <item> to <player>")}, true
+	case "who":
+		return who(), true
+	case "savegame", "loadgame":
+		return []string{text("the-house-is-shared", "The house is shared, so there's no saving or loading it.")}, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lineWriter passes on everything written to it, a write at a time
+type lineWriter chan string
+
+func (w lineWriter) Write(b []byte) (int, error) {
+	w <- string(b)
+	return len(b), nil
+}
+
+func TestLeavingTheHouseLeavesTheBackpacksContents(t *testing.T) {
+	p, e := joinHouse("ann", ioutil.Discard)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	inv := p.s.Game.Inventory
+	inv["sock"] = &Item{Name: "sock", Weight: 1, Discovered: true}
+	inv["backpack"].Contents = map[string]*Item{"rock": {Name: "rock", Weight: 1, Discovered: true}}
+	room := house.rooms[p.s.Game.CurRoom]
+	leaveHouse(p)
+
+	for _, name := range []string{"sock", "rock"} {
+		if room.Items[name] == nil {
+			t.Errorf("the %s wasn't left behind", name)
+		}
+	}
+	for _, name := range []string{"backpack", "shrink ray"} {
+		if room.Items[name] != nil {
+			t.Errorf("the %s was left behind", name)
+		}
+	}
+}
+
+func TestOtherPlayersSeeSomeoneWalkIn(t *testing.T) {
+	ann, e := joinHouse("ann", ioutil.Discard)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer leaveHouse(ann)
+
+	heard := make(lineWriter, 32)
+	bob, e := joinHouse("bob", heard)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer leaveHouse(bob)
+
+	engineMu.Lock()
+	ann.s.Game.CurRoom = "Kitchen"
+	bob.s.Game.CurRoom = "Pantry"
+	engineMu.Unlock()
+
+	// ann plays from two terminals at once
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { playSharedTurn(ann, "go to pantry"); wg.Done() }()
+	go func() { playSharedTurn(ann, "look"); wg.Done() }()
+	wg.Wait()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case m := <-heard:
+			if strings.Contains(m, "ann walks in from the kitchen") {
+				return
+			}
+		case <-timeout:
+			t.Fatal("bob didn't see ann walk in")
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// what an NPC is doing
+const (
+	Asleep    = "asleep"
+	Wandering = "wandering"
+	Following = "following"
+)
+
+// a character who moves around the house on their own
+type NPC struct {
+	Name         string
+	Description  string
+	Room         string   // where the NPC is now
+	Home         string   // where the NPC sleeps
+	Avoid        []string // rooms the NPC cannot get to
+	State        string
+	Turns        int // turns spent in the current state
+	SleepTurns   int // how long the NPC sleeps before wandering
+	WanderTurns  int // how long the NPC wanders before going home
+	FollowTurns  int // how long the NPC follows the player when summoned
+	SummonItem   string
+	AsleepHere   string // describes the NPC sleeping in the player's room
+	AwakeHere    string // describes the NPC awake in the player's room
+	Wakes        string
+	Arrives      string
+	Leaves       string // %s is the room the NPC leaves for
+	Follows      string
+	Summoned     string // %s is the room the NPC is summoned to
+	SummonedHere string // summoned while already in the player's room
+	Interactions map[string]*Interaction
+}
+
+// something the player can do with an NPC, such as "pet dog"
+type Interaction struct {
+	Requires string // an item the player must have
+	Message  string
+	Carries  bool // the NPC carries the player home
+}
+
+var npcs = make(map[string]*NPC) // map of NPCs
+var cast map[string]*NPC         // NPCs as they are when a game begins
+
+// loadNPCs reads NPC definitions from the 'npcs' directory relative to the
+// game's home directory.
+func loadNPCs() {
+	files, e := ioutil.ReadDir("npcs")
+	if e != nil {
+		log.Fatal(e)
+	}
+
+	for _, f := range files {
+		matched, _ := regexp.MatchString(`\.json`, f.Name())
+		if matched == true {
+			npcJson, e := ioutil.ReadFile("npcs/" + f.Name())
+
+			if e != nil {
+				log.Fatal(e)
+			}
+
+			var n NPC
+			if e := json.Unmarshal([]byte(npcJson), &n); e != nil {
+				log.Fatalf("npcs/%s: %v", f.Name(), e)
+			}
+			npcs[n.Name] = &n
+		}
+	}
+
+	for _, n := range npcs {
+		if _, ok := rooms[n.Room]; !ok {
+			panic(fmt.Sprintf("%s starts in a room that doesn't exist: %s", n.Name, n.Room))
+		}
+	}
+
+	localizeNPCs(npcs)
+	cast = copyNPCs(npcs)
+}
+
+// copyNPCs returns a deep copy of the NPCs in 'm'
+func copyNPCs(m map[string]*NPC) map[string]*NPC {
+	b, e := json.Marshal(m)
+	if e != nil {
+		log.Fatal(e)
+	}
+
+	c := make(map[string]*NPC)
+	if e := json.Unmarshal(b, &c); e != nil {
+		log.Fatal(e)
+	}
+
+	return c
+}
+
+// describeNPCs mentions the NPCs in the current room
+func describeNPCs() {
+	for _, name := range npcNames() {
+		if n := npcs[name]; n.Room == curRoom.Name {
+			if n.State == Asleep {
+				narrate(n.AsleepHere)
+			} else {
+				narrate(n.AwakeHere)
+			}
+		}
+	}
+}
+
+// lookAtNPC prints the description of an NPC in the current room
+func lookAtNPC(name string) bool {
+	if n, ok := npcs[name]; ok && n.Room == curRoom.Name {
+		narrate(n.Description)
+		return true
+	}
+
+	return false
+}
+
+// canVisit reports whether NPC 'n' can get to 'room'
+func (n *NPC) canVisit(room string) bool {
+	for _, r := range n.Avoid {
+		if r == room {
+			return false
+		}
+	}
+
+	return true
+}
+
+// moveTo moves NPC 'n' to 'room', letting the player see them come and go
+func (n *NPC) moveTo(room string, arrival string) {
+	if room == n.Room {
+		return
+	}
+
+	if n.Room == curRoom.Name {
+		narrate(fmt.Sprintf("\n"+n.Leaves, strings.ToLower(localName(room))))
+	} else if room == curRoom.Name {
+		narrate("\n" + arrival)
+	}
+
+	n.Room = room
+}
+
+// setState starts NPC 'n' doing something new
+func (n *NPC) setState(state string) {
+	n.State = state
+	n.Turns = 0
+}
+
+// npcNames returns the names of the NPCs in order, so they take their turns
+// the same way each time a seeded game is played
+func npcNames() []string {
+	var names []string
+	for name := range npcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// moveNPCs lets each NPC take a turn after the player has taken theirs
+func moveNPCs() {
+	for _, name := range npcNames() {
+		n := npcs[name]
+		n.Turns++
+
+		switch n.State {
+		case Asleep:
+			if n.Turns >= n.SleepTurns {
+				n.setState(Wandering)
+				if n.Room == curRoom.Name {
+					narrate("\n" + n.Wakes)
+				}
+			}
+		case Following:
+			if n.Turns >= n.FollowTurns {
+				n.setState(Wandering)
+			} else if n.canVisit(curRoom.Name) {
+				n.moveTo(curRoom.Name, n.Follows)
+			}
+		case Wandering:
+			if n.Turns >= n.WanderTurns {
+				n.moveTo(n.Home, n.Arrives)
+				if n.Room == n.Home {
+					n.setState(Asleep)
+				}
+			} else if dice.Intn(2) == 0 {
+				var exits []string
+				for _, e := range rooms[n.Room].Exits {
+					if n.canVisit(e) {
+						exits = append(exits, e)
+					}
+				}
+
+				if len(exits) > 0 {
+					n.moveTo(exits[dice.Intn(len(exits))], n.Arrives)
+				}
+			}
+		}
+	}
+}
+
+// summonNPC brings NPC 'name' to the player, who then rides them home. The
+// NPC follows the player for a while afterwards.
+func summonNPC(name string) {
+	n := npcs[name]
+
+	if curRoom.Name == n.Home {
+		narrate(n.SummonedHere)
+		n.Room = n.Home
+		n.setState(Asleep)
+		return
+	}
+
+	if n.Room != curRoom.Name {
+		narrate(fmt.Sprintf(n.Summoned, strings.ToLower(localName(curRoom.Name))))
+		n.Room = curRoom.Name
+	}
+	rideNPC(n)
+	n.setState(Following)
+}
+
+// rideNPC carries the player to the home of NPC 'n'
+func rideNPC(n *NPC) {
+	if climbedUp {
+		if curRoom.Name == "Dining Room" {
+			say("with-a-running-start", "With a running start you leap off of the dining room table.")
+		} else if curRoom.Name == "Basement Lab" {
+			say("with-a-running-start-2", "With a running start you leap off of the desk.")
+		} else if curRoom.Name == "Pantry" {
+			say("with-a-running-start-3", "With a running start you leap off of the shelves.")
+		}
+		climbedUp = false
+	}
+
+	if ride, ok := n.Interactions["ride"]; ok {
+		narrate(ride.Message)
+	} else {
+		say("you-hold-on-tight", "You hold on tight and the %s carries you off.", n.Name)
+	}
+	enterRoom(rooms[n.Home])
+	n.Room = n.Home
+}
+
+// interactWithNPC carries out 'verb' on the NPC 'name', as described in the
+// NPC's data. It returns false if no NPC understands 'verb'.
+func interactWithNPC(verb string, name string) bool {
+	understood := false
+	for _, n := range npcs {
+		if _, ok := n.Interactions[verb]; ok {
+			understood = true
+		}
+	}
+
+	if !understood {
+		return false
+	}
+
+	n, ok := npcs[name]
+	if !ok || n.Room != curRoom.Name {
+		say("not-found", "%s not found.", name)
+		return true
+	}
+
+	i, ok := n.Interactions[verb]
+	if !ok {
+		say("you-cant-the", "You can't %s the %s.", verb, name)
+		return true
+	}
+
+	if i.Requires != "" {
+		if _, ok := inventory[i.Requires]; !ok {
+			say("you-need-the-for", "You need the %s for that.", i.Requires)
+			return true
+		}
+	}
+
+	if i.Carries {
+		if curRoom.Name == n.Home {
+			say("the-is-already-home", "The %s is already home. It's time for a nap.", name)
+			n.setState(Asleep)
+			return true
+		}
+		rideNPC(n)
+		n.setState(Following)
+		describeRoom()
+		return true
+	}
+
+	if n.State == Asleep {
+		say("the-wakes-up", "The %s wakes up.", name)
+		n.setState(Wandering)
+	}
+	narrate(i.Message)
+
+	return true
+}
@@ -1,21 +1,24 @@
 package main
 
 import (
-	"bufio"
 	"fmt"
-	"os"
 	"regexp"
 	"strings"
 )
 
 // lookAtRoom repeats the long form explanation of a room.
 func lookAtRoom() {
-	fmt.Println(curRoom.LongDesc)
-	fmt.Println("\nSome of the things that you see include:")
-	for _, item := range curRoom.Items {
-		if item.Discovered == true {
-			fmt.Printf("     %s\n", item.Name)
-		}
+	if curRoom.Light == Dark && !canSee() {
+		say("pitch-dark", darkMessage)
+		return
+	}
+
+	emit(Event{Kind: RoomDescribed, Room: curRoom.Name, Text: curRoom.LongDesc})
+	listRoomItems()
+	describeNPCs()
+	describeEncounters()
+	if accessible {
+		listExits()
 	}
 }
 
@@ -27,185 +30,283 @@ func lookAtItem(item string) {
 		return
 	}
 
-	if curRoom.Name == "Yard" && item == "eagle" {
-		lookAtEagle()
+	if item == "me" || item == "myself" || item == "yourself" {
+		lookAtYourself()
 		return
 	}
 
-	if val, ok := inventory[item]; ok { // check player inventory for requested item
-		fmt.Println(val.Description)
-	} else if val, ok := curRoom.Items[item]; ok { // check the room for requested item
-		if val.Discovered == true {
-			fmt.Println(val.Description)
-		} else if val.Name != "dog" {
-			fmt.Println("You cannot see that, at least not from here!")
+	if enc, ok := encounterHere(item); ok {
+		if !enc.react("look") {
+			narrate(enc.States[enc.State].Here)
+		}
+		return
+	}
+
+	if where, ok := findItem(inventory, item); ok { // check player inventory for requested item
+		val := where[item]
+		narrate(val.Description)
+		if val.IsContainer {
+			listContents(val)
+		}
+	} else if where, ok := findItem(curRoom.Items, item); ok { // check the room for requested item
+		val := where[item]
+		if val.Discovered && !visible(val) {
+			say("its-too-dark-to", "It's too dark to make that out.")
+			return
+		} else if val.Discovered == true {
+			narrate(val.Description)
+			if val.IsContainer {
+				listContents(val)
+			}
+		} else {
+			say("you-cannot-see-that", "You cannot see that, at least not from here!")
 			return
 		}
 
-		if val.ContainsHiddenObject == true {
+		if val.ContainsHiddenObject && !canSee() {
+			say("its-too-dim-to", "It's too dim to tell if there's anything else there. You need a light.")
+		} else if val.ContainsHiddenObject == true {
 			if hiddenThing, ok := curRoom.Items[val.HiddenObject]; ok {
-				fmt.Println(val.DiscoveryStatement)
+				narrate(val.DiscoveryStatement)
 				hiddenThing.Discovered = true
+				exitsChanged()
 			}
 			val.ContainsHiddenObject = false
 		}
-	} else {
-		fmt.Printf("%s not found.\n", item)
+	} else if !lookAtNPC(item) {
+		say("not-found", "%s not found.", item)
 	}
 }
 
 // takeItem places an object which is small enough into the player's inventory
 func takeItem(item string) {
-	if val, ok := curRoom.Items[item]; ok {
-		if val.Discovered == false {
-			fmt.Printf("%s not found.\n", item)
+	if where, ok := findItem(curRoom.Items, item); ok {
+		val := where[item]
+		if !visible(val) {
+			say("not-found", "%s not found.", item)
 			return
 		}
 
-		if val.IsFeature == false && val.TooBig == false {
+		if val.IsFeature == false && !val.tooBig() {
+			if !canCarry(inventory, val) {
+				return
+			}
 			inventory[item] = val
-			delete(curRoom.Items, item) // remove item from room after picking it up
-			fmt.Printf("You have picked up the %s.\nIt is now in your INVENTORY.\n", item)
-		} else if val.TooBig && !val.IsFeature {
-			fmt.Printf("%s is too big to pick up!\nWhy don't you try to SHRINK it first?\n", item)
+			delete(where, item) // remove item from room after picking it up
+			inventoryChanged()
+			say("you-have-picked-up", "You have picked up the %s.\nIt is now in your {verb:inventory}.", item)
+		} else if val.tooBig() && !val.IsFeature {
+			say("is-too-big-to", "%s is too big to pick up!\nWhy don't you try to {verb:shrink} it first?", item)
 		} else {
-			fmt.Println("You cannot pick that up!")
+			say("you-cannot-pick-that", "You cannot pick that up!")
 		}
 
 	} else {
-		fmt.Printf("%s not found.\n", item)
+		say("not-found", "%s not found.", item)
 	}
 }
 
 // dropItem drops an item in the current room and removes the item from the player's inventory
 func dropObject(item string) {
 	if val, ok := inventory[item]; ok {
+		if !canLetGo(val) {
+			return
+		}
+
 		curRoom.Items[item] = val
 		delete(inventory, item)
-		fmt.Printf("You dropped the %s in the %s.\n", item, curRoom.Name)
+		inventoryChanged()
+		say("you-dropped-the-in", "You dropped the %s in the %s.", item, curRoom.Name)
 	} else {
-		fmt.Printf("%s not found.\n", item)
-	}
-}
-
-// listInventory lists the contents of your inventory.
-func listInventory() {
-	for key := range inventory {
-		fmt.Println(key)
+		say("not-found", "%s not found.", item)
 	}
 }
 
 // moveToRoom takes a requested exit and moves the player there if the exit exists
 func moveToRoom(exit string) {
-	if climbedUp {
-		fmt.Printf("Make sure you CLIMB DOWN before you try to go anywhere!\n")
+	// If the exit requested by a user matches an entry in the list of
+	// room aliases, then the room name becomes the requested exit.
+	for key, val := range roomAliases {
+		matched, _ := regexp.MatchString(key, exit)
+		if matched == true {
+			exit = val
+		}
+	}
+
+	p := findPassage(exit)
+
+	if climbedUp && (p == nil || !p.Climbed) {
+		say("make-sure-you-climb", "Make sure you {verb:climb down} before you try to go anywhere!")
 		return
 	}
 
-	if eagleWatching {
-		fmt.Printf("AGH! The eagle is taking his revenge!\n")
-		tauntTheEagle()
+	if reactToAll("leave") {
 		return
 	}
 
-	b := checkExit() // verify we have items needed to leave
-	if !b {
-		fmt.Printf("You cannot leave because %s.\n", curRoom.ExitBlock)
+	if d, ok := directions[strings.ToLower(exit)]; ok && p == nil {
+		say("you-cant-go-from", "You can't go %s from here.", d)
+		return
+	} else if p == nil {
+		say("is-not-a-valid", "%s is not a valid exit.", exit)
 		return
 	}
 
-	// If the exit requested by a user matches an entry in the list of
-	// room aliases, then the room name becomes the requested exit.
-	for key, val := range roomAliases {
-		matched, _ := regexp.MatchString(key, exit)
-		if matched == true {
-			exit = val
+	if !p.If.holds() || p.Climbed && !climbedUp {
+		if p.Block != "" {
+			narrate(p.Block)
+		} else {
+			say("you-cant-get-there", "You can't get there from here.")
+		}
+		return
+	}
+
+	if !exert(p.Effort) {
+		return
+	}
+
+	goThrough(p)
+}
+
+// findPassage returns the way out of the current room to 'exit', which is a
+// room, a direction, one of the exit's aliases, or a feature like the laundry
+// chute. It returns nil if there is no such exit.
+func findPassage(exit string) *Exit {
+	name := strings.ToLower(exit)
+	if d, ok := directions[name]; ok {
+		name = d
+	}
+
+	for _, p := range curRoom.Passages {
+		if hidden(p) {
+			continue
+		}
+
+		if p.Via == name || p.Direction == name {
+			return p
+		}
+
+		for _, a := range p.Aliases {
+			if a == name {
+				return p
+			}
 		}
 	}
 
 	for _, e := range curRoom.Exits {
 		if e == exit { // check that requested exit is valid
-			if val, ok := rooms[exit]; ok {
-				if curRoom.Name == "Attic" && val.Name == "Upstairs Hallway" {
-					if _, ok := inventory["paper"]; ok {
-						useTheThread()
-					} else {
-						fmt.Printf("You might be forgetting something important, but you can always come back.\n\n")
-						useTheThread()
-					}
-				}
-				if curRoom.Name == "Upstairs Hallway" && val.Name == "Attic" {
-					if _, ok := inventory["thread"]; ok {
-						useTheThread()
-					} else {
-						fmt.Printf("Did you drop the thread somewhere?\nYou'll need to throw it up to reach the bottom of the attic ladder.\n") 
-						return
-					}
-				}
-				if curRoom.Name == "Upstairs Hallway" && val.Name == "Large Bedroom" && val.Visited == false {
-					bounceEnterLargeBedroom()
-				}
-				if curRoom.Name == "Staircase" && val.Name == "Downstairs Hallway" {
-					downTheBanister()
-				}
-				if curRoom.Name == "Downstairs Hallway" && val.Name == "Staircase" {
-					climbTheStairs()
+			for _, p := range curRoom.Passages {
+				if p.Via == "" && p.To == e {
+					return p
 				}
+			}
+			return &Exit{To: e}
+		}
+	}
 
-				curRoom = val // if found, the exit is the new current room
+	return nil
+}
 
-				if curRoom.Visited == false { // have we been here before?
-					curRoom.Visited = true
-					fmt.Println(curRoom.LongDesc)
-				} else {
-					fmt.Println(curRoom.Description)
-				}
+// hidden reports whether passage 'p' goes through a feature of the current
+// room which the player hasn't discovered yet
+func hidden(p *Exit) bool {
+	item, ok := curRoom.Items[p.Via]
+	return p.Via != "" && ok && !item.Discovered
+}
 
-				fmt.Println("\nSome of the things that you see include:")
+// listExits tells the player the ways out of the current room
+func listExits() {
+	emit(Event{Kind: ExitsListed, Exits: ways()})
+}
 
-				for _, item := range curRoom.Items {
-					if item.Discovered == true {
-						fmt.Printf("     %s\n", item.Name)
-					}
-				}
+// goThrough takes the player through passage 'p', telling them how it went
+func goThrough(p *Exit) {
+	val := rooms[p.To]
 
-				return
-			}
+	for _, l := range p.Travel {
+		if l.If.holds() {
+			narrate(l.Text)
 		}
 	}
-	fmt.Printf("%s is not a valid exit.\n", exit)
+
+	if p.First != "" && val.Visited == false {
+		narrate(p.First)
+	}
+
+	if n, ok := npcs[p.Carrier]; ok {
+		if _, ok := inventory[n.SummonItem]; ok {
+			callTheDog(n.SummonItem)
+		} else {
+			n.Room = n.Home
+			n.setState(Wandering)
+		}
+	}
+
+	climbedUp = false
+	enterRoom(val) // the exit is the new current room
+	describeRoom()
 }
 
-// checkExit verifies the player has the item necessary to exit a room
-func checkExit() bool {
-	if len(curRoom.ExitItems) == 0 {
-		return true
+// describeRoom tells the player about the room they've just entered, in as
+// much detail as they've asked for with "verbose", "brief" or "superbrief".
+// "look" always describes it in full.
+func describeRoom() {
+	if curRoom.Light == Dark && !canSee() {
+		say("pitch-dark", darkMessage)
+		return
 	}
 
-	obj := curRoom.ExitItems[0]
-	if _, ok := inventory[obj]; ok {
-		return true
+	desc := curRoom.Description
+	switch {
+	case verbosity == Superbrief:
+		curRoom.Visited = true
+		desc = sentence(localName(curRoom.Name))
+	case verbosity == Verbose || curRoom.Visited == false: // have we been here before?
+		curRoom.Visited = true
+		desc = curRoom.LongDesc
 	}
+	emit(Event{Kind: RoomDescribed, Room: curRoom.Name, Text: desc})
 
-	return false
+	// superbrief leaves the things in the room for "look" to list
+	if verbosity != Superbrief {
+		listRoomItems()
+	}
+	describeNPCs()
+	describeEncounters()
+	if accessible {
+		listExits()
+	}
 }
 
 // haveAllItems checks if player's inventory has all the items needed to win
-// the game. Should only be called if player is in the attic.
+// the game, whether on their own or made into something else
 func haveAllItems() bool {
+	return score(inventory) == len(winningItems)
+}
+
+// score counts how many of the items needed to win the game are in the
+// inventory 'inv', including those put into or made into other items
+func score(inv map[string]*Item) int {
+	have := make(map[string]bool)
+	for _, item := range inv {
+		for _, p := range parts(item) {
+			have[p] = true
+		}
+	}
+
+	n := 0
 	for _, item := range winningItems {
-		_, ok := inventory[item]
-		if !ok {
-			return false
+		if have[item] {
+			n++
 		}
 	}
 
-	return true
+	return n
 }
 
 // help prints a subset of verbs the game understands
 func help() {
-	m := fmt.Sprintf(`Here are some of the commands the game understands:
+	m := text("help", `Here are some of the commands the game understands:
 
 	inventory :: Lists the contents of your inventory.
 
@@ -216,7 +317,17 @@ func help() {
 	look at <feature or object> :: Prints the description of an item.
 
 	go :: "go <room>" or "go to <room>" - Proceed through
-		the indicated exit to the next room.
+		the indicated exit to the next room. You can also go in a
+		direction, like "go north" or just "n", "up" or "out".
+
+	exits :: List the ways out of the current room.
+
+	map :: Draw a map of the rooms you know on this floor.
+
+	light :: Light something, like a candle, from a flame, or switch on
+		a flashlight. Dark rooms need a light.
+
+	blow out :: Put out a light, so it lasts for later.
 
 	take :: Acquire an object, putting it into your inventory.
 
@@ -225,14 +336,35 @@ func help() {
 	drop :: Remove an object from your inventory, dropping it in
 		the current room.
 
+	open, close :: Open or close a container, like a cabinet.
+
+	lock, unlock :: Lock or unlock a container with its key.
+
+	put :: "put <item> in <container>" - Put something away.
+
+	take from :: "take <item> from <container>" - Get something back out.
+
+	look in :: "look in <container>" - See what's inside.
+
+	combine :: "combine <item> and <item>" - Make something new.
+		Parts for the shrink ray go in it: "put <part> in shrink ray".
+
 	eat :: Restore your strength by eating an item.
 
+	rest :: Sit down for a while and get a little of your strength back.
+
 	pull :: See take.
 
 	whistle :: With the right item at hand, you can whistle to
 		summon the family pet.
 
-	call :: Call your parents to come and fix things for you.
+	pet, feed, ride :: Make friends with the dog, if you can find him.
+
+	call :: Call your parents to ask for a hint, or to come and fix
+		things for you.
+
+	talk :: "talk to <character>" - Have a chat. Type the number of what
+		you want to say, or {verb:bye} to stop talking.
 
 	enter :: Type a secret password into a computer.
 
@@ -243,13 +375,17 @@ func help() {
 
 	taunt :: Pick a fight!
 
+	throw :: Throw something from your inventory. Go on, throw it.
+
 	jump :: Get vertical!
 
 	slide :: Travel quickly.
 
 	yank :: See take.
 
-	shrink :: Make a big thing a smaller thing.
+	shrink :: Make a big thing a smaller thing, one size at a time.
+
+	grow :: Make a shrunken thing bigger again, up to the size it was.
 
 	cut :: Cut an item.
 
@@ -258,66 +394,36 @@ func help() {
 	loadgame :: Confirms that this really is desired, then loads
 		the game state from a file.
 
+	repeat :: Hear what the game last said again.
+
+	verbose, brief, superbrief :: Describe rooms in full every time you
+		enter them, only the first time, or just give their names.
+
 	quit :: save game and then exit.
 
 	help :: Print this message.`)
 
-	fmt.Println(m)
-}
-
-func shrinkObject(item string) {
-	if _, ok := inventory["shrink ray"]; ok {
-		if item == "shrink ray" {
-			fmt.Println("You can't shrink the shrink ray")
-			return
-		} else if val, ok := curRoom.Items[item]; ok {
-			if val.IsFeature == false {
-				if val.TooBig == true {
-					fmt.Println("SHRINKING!")
-					val.TooBig = false
-					fmt.Println("This item is now small enough to collect. You can TAKE it now.")
-				} else {
-					fmt.Println("I don't think that can get any smaller. Did you try to just TAKE it?")
-				}
-			} else {
-				fmt.Println("You can't shrink this. Mom and Dad might notice!")
-			}
-		} else {
-			fmt.Printf("There is no '%s' in this room to shrink!\n", item)
-		}
-	} else {
-		fmt.Println("You need the shrink ray to shrink things.")
-	}
+	narrate(m)
 }
 
+// callTheDog blows the whistle 'item', summoning whoever comes running when
+// they hear it
 func callTheDog(item string) {
 	if _, ok := inventory[item]; ok {
-		if curRoom.Name == "Staircase" {
-			fmt.Println(curRoom.Items["dog"].DiscoveryStatement)
-		} else {
-			fmt.Printf("You hear the padding footsteps of your loyal steed.\nHe comes loping into the %s.\n", strings.ToLower(curRoom.Name))
-			if climbedUp {
-				if curRoom.Name == "Dining Room" {
-					fmt.Printf("With a running start you leap off of the dining room table.\n")
-				} else if curRoom.Name == "Basement Lab" {
-					fmt.Printf("With a running start you leap off of the desk.\n")
-				} else if curRoom.Name == "Pantry" {
-					fmt.Printf("With a running start you leap off of the shelves.\n")
-				}
-				climbedUp = false
+		for _, n := range npcs {
+			if n.SummonItem == item {
+				summonNPC(n.Name)
+				return
 			}
-			fmt.Println("You grab onto him and he starts running.")
-			fmt.Println("When he finally slows down at the top of the stairs you jump off.\n")
-			curRoom = rooms["Staircase"]
 		}
-	} else {
-		fmt.Println("The dog can't hear you without the dog whistle")
 	}
+
+	say("the-dog-cant-hear", "The dog can't hear you without the dog whistle")
 }
 
 func callYourParents() {
 	if haveAllItems() {
-		fmt.Println("But you're so close you just have to get back to the attic!")
+		say("but-youre-so-close", "But you're so close you just have to get back to the attic!")
 
 	} else {
 		gameOver = true
@@ -325,191 +431,118 @@ func callYourParents() {
 
 }
 
-// eatItem searches the player's inventory for an edible item and consumes it
-func eatItem(item string) {
-	if val, ok := inventory[item]; ok {
-		if val.IsEdible {
-			fmt.Println("You just eat one corn flake. It's big enough to quiet your appetite given\nyour current stature. You save the rest of the box so you can\nfix the shrink ray before mom and dad get home.")
-		} else {
-			fmt.Printf("I know you're hangry. But %s is not food!\n", item)
-		}
-	} else {
-		fmt.Printf("%s is not in your backpack.\n", item)
-	}
-}
-
 // enterThePassword types the secret password into the computer
 func enterThePassword() {
 	if _, ok := inventory["password"]; ok {
 		if curRoom.Name == "Basement Lab" && curRoom.Items["computer"].Discovered {
-			fmt.Println("TAKE the SOFTWARE you need. Make sure to LOOK at it too.")
+			say("take-the-software-you", "{verb:take} the {item:software} you need. Make sure to {verb:look} at it too.")
 			curRoom.Items["software"].Discovered = true
 		} else {
-			fmt.Println("There's nothing that needs a password here.")
+			say("theres-nothing-that-needs", "There's nothing that needs a password here.")
 		}
 	} else {
-		fmt.Println("I don't think you know the password.")
+		say("i-dont-think-you", "I don't think you know the password.")
 	}
 }
 
+// the features which can be climbed, by the room they're in
+var climbable = map[string]string{
+	"Basement Lab": "desk",
+	"Pantry":       "paper towels",
+	"Dining Room":  "dining room table",
+}
+
 func climbStuff(item string) {
 	if curRoom.Name == "Basement Lab" && item == "desk" {
+		if !exert(climbEffort) {
+			return
+		}
 		climbedUp = true
-		fmt.Println("You climb up the desk and are face to face with the COMPUTER.")
-		fmt.Println("It seems locked. Why don't you take a LOOK?")
+		say("you-climb-up-the", "You climb up the desk and are face to face with the {item:computer}.")
+		say("it-seems-locked-why", "It seems locked. Why don't you take a {verb:look}?")
 		curRoom.Items["computer"].Discovered = true
 	} else if curRoom.Name == "Large Bedroom" && item == "desk" {
-		fmt.Println("You'd better not climb on your parents' desk!")
+		say("youd-better-not-climb", "You'd better not climb on your parents' desk!")
 	} else if curRoom.Name == "Pantry" && item == "paper towels" {
+		if !exert(climbEffort) {
+			return
+		}
 		climbedUp = true
-		fmt.Println("From up on the paper towels you can get a better look at the shelves.")
-		fmt.Println("There is a box of CORN FLAKES pushed all the way back on one of the shelves.\nWeren't you looking for corn flakes?")
+		say("from-up-on-the", "From up on the paper towels you can get a better look at the shelves.")
+		say("there-is-a-box", "There is a box of {item:corn flakes} pushed all the way back on one of the shelves.\nWeren't you looking for corn flakes?")
 		curRoom.Items["corn flakes"].Discovered = true
 	} else if curRoom.Name == "Dining Room" && item == "dining room table" {
+		if !exert(climbEffort) {
+			return
+		}
 		climbedUp = true
-		fmt.Println("From on top of the table you can see more.")
+		say("from-on-top-of", "From on top of the table you can see more.")
 		curRoom.Items["candelabra"].Discovered = true
-		fmt.Println("There's wax everywhere but it looks like there might still be a bit of\ncandle left. LOOK at the CANDELABRA to investigate.")
+		say("theres-wax-everywhere-but", "There's wax everywhere but it looks like there might still be a bit of\ncandle left. {verb:look} at the {item:candelabra} to investigate.")
 	} else if item == "down" {
 		climbedUp = false
-		fmt.Println("You climb back down to the ground before you get dizzy!")
+		say("you-climb-back-down", "You climb back down to the ground before you get dizzy!")
 	} else if _, ok := curRoom.Items[item]; !ok {
-		fmt.Printf("%s not found.\n", item)
+		say("not-found", "%s not found.", item)
 	} else {
-		fmt.Printf("You can't climb on that!\n")
-	}
-}
-
-func climbTheStairs() {
-	fmt.Println("Oof that's a lot of stairs to climb!")
-	if _, ok := inventory["dog whistle"]; ok {
-		fmt.Println("But you have the dog whistle!")
-		callTheDog("dog whistle")
-	} else {
-		fmt.Println("You scream in frustration and your wailing wakes the dog up.")
-		fmt.Println("He takes pity on you and picks you up by the scruff and drops you off at the top of the stairs.")
-		fmt.Println("You're drenched and smell terrible now but at least you didn't have to climb those stairs")
+		say("you-cant-climb-on", "You can't climb on that!")
 	}
 }
 
 func cutStuff(item string) {
 	if curRoom.Name == "Family Room" && item == "copper wire" || curRoom.Name == "Living Room" && item == "couch stuffing" {
-		fmt.Println("snip snip")
+		say("snip-snip", "snip snip")
 		takeItem(item)
 	} else if _, ok := curRoom.Items[item]; !ok {
-		fmt.Printf("%s not found.\n", item)
-	} else {
-		fmt.Println("Please don't cut that.")
-	}
-}
-
-func downTheBanister() {
-	if _, ok := inventory["scarf"]; ok {
-		fmt.Println("\nYou use the scarf to slide quickly and safely down the banister.\n")
-	} else {
-		fmt.Println("\nYou try to slide down the banister but your jeans don't slide down easily\nso it's more of a scooch.")
-		fmt.Println("After a couple minutes of struggling you're sweaty and have worn a hole\ndown in the seat of your pants.")
-		fmt.Println("You fall off the banister halfway down and tumble down the rest of the stairs.")
-		fmt.Println("The dog just raises his head and looks at you while you flail helplessly.")
-		fmt.Println("You land with another thud, thankfully nothing seems broken.")
-		fmt.Println("You should have grabbed that silky scarf.\n")
-	}
-}
-
-func lookAtEagle() {
-	fmt.Printf("You look directly into the eagle's eyes.\nHe has a look on his face screaming 'YOU WANNA FIGHT, BRO?' as he flies towards you.\n\n")
-	eagleWatching = true
-
-	if _, ok := inventory["umbrella"]; ok {
-		fmt.Println("But you have the umbrella! You can use it to hide from the eagle.\nHe'll be distracted by the bright colors.")
-		fmt.Println("If you want to use the umbrella to hide from the eagle say: use umbrella")
-		fmt.Println("If you want to be taken by the eagle say: taunt eagle")
-		fmt.Println("You can also try your luck acting like you never looked at the eagle.\nWho knows? He might just leave you alone.")
-	} else {
-		fmt.Println("The eagle swoops down and picks you up. You can see your whole neighborhood\nfrom up here!\n\nYou manage to wriggle free and drop down the chimney. You climb down\ntowards a bit of sunlight, and exit through a small hole in the chimney\ninto the large bedroom.\n")
-		curRoom = rooms["Large Bedroom"]
-		eagleWatching = false
-		lookAtRoom()
-	}
-}
-
-func useTheThread() {
-	if _, ok := inventory["thread"]; ok {
-		if curRoom.Name == "Upstairs Hallway" {
-			threadUp := fmt.Sprintf(`You throw the thread up like a lasso and it attaches to the bottom of the
-ladder to the attic. You free climb up it like the Man in Black from
-the Princess Bride on the Cliffs of Insanity.
-
-You look so cool.
-`)
-			fmt.Println(threadUp)
-		} else if curRoom.Name == "Attic" {
-			threadDown := fmt.Sprintf(`You tie one end of the thread around your waist and the other around the top
-rung of the attic ladder. Here goes nothing!
-
-You leap out of the attic door and the thread acts as a bungee. It catches you
-right before you smash into the floor of the upstairs hallway.
-
-As you're hanging, catching your breath, it unravels from the ladder and you
-drop with a small thud. You gather up the thread and put it in your backpack.
-`)
-			fmt.Println(threadDown)
-		}
+		say("not-found", "%s not found.", item)
 	} else {
-		fmt.Println("You don't have the thread.")
+		say("please-dont-cut-that", "Please don't cut that.")
 	}
 }
 
-func bounceEnterLargeBedroom() {
-	fmt.Println("The door to the large bedroom is closed and you can't reach it at this size.")
-	fmt.Println("You take a running start and hurl yourself at your dad's exercise ball.")
-	fmt.Println("You bounce off of it with a loud *VWOMP* and grab onto the door handle.")
-	fmt.Println("You're just heavy enough to make the handle turn and the door creaks open.")
-	fmt.Println("You drop to the floor and walk right in.\n")
-}
-
+// useTheUmbrella opens the umbrella, which might scare something off
 func useTheUmbrella() {
-	if _, ok := inventory["umbrella"]; ok && curRoom.Name == "Yard" {
-		eagleWatching = false
-		fmt.Println("You open the umbrella and are completely hidden from the eagle.")
-		fmt.Println("The bright colors calm him and he no longer wants to fight.\nThe eagle flies away.")
-	} else if _, ok := inventory["umbrella"]; ok && curRoom.Name != "Yard" {
-		fmt.Println("You can't open the umbrella inside!")
+	if _, ok := inventory["umbrella"]; !ok {
+		say("you-dont-have-an", "You don't have an umbrella.")
+	} else if reactToAll("use umbrella") {
+		return
+	} else if curRoom.Name == "Yard" || curRoom.Name == "Front Porch" {
+		say("you-open-the-umbrella", "You open the umbrella and twirl it around. Nobody is impressed.")
 	} else {
-		fmt.Println("You don't have an umbrella.")
+		say("you-cant-open-the", "You can't open the umbrella inside!")
 	}
 }
 
-func tauntTheEagle() {
-	if curRoom.Name == "Yard" {
-		eagleWatching = false
-		fmt.Printf("The eagle has heard your taunts and it has made him mad!\n\n")
-		fmt.Println("The eagle swoops down and picks you up. You can see your whole neighborhood\nfrom up here!\n\nYou manage to wriggle free and drop down the chimney. You climb down\ntowards a bit of sunlight, and exit through a small hole in the chimney\ninto the large bedroom.")
-		curRoom = rooms["Large Bedroom"]
-		lookAtRoom()
-	} else {
-		fmt.Println("There's nobody here to taunt but yourself.")
+// tauntTheEagle picks a fight with 'name', if they are around to hear it
+func tauntTheEagle(name string) {
+	if enc, ok := encounterHere(name); !ok || !enc.react("taunt") {
+		say("theres-nobody-here-to", "There's nobody here to taunt but yourself.")
 	}
 }
 
+// slideDownJumpIn takes the player through a passage like the laundry chute
 func slideDownJumpIn(userInput []string) {
-	if curRoom.Name == "Small Bedroom" {
-		if (len(userInput) > 1 && userInput[1] == "laundry") || len(userInput) > 2 && userInput[2] == "laundry" {
-			fmt.Println("HERE GOES NOTHING!")
-			fmt.Println("With all of your strength you jump into the gaping opening of the laundry chute.\nDirty clothes and dust bunnies zip past as you gain speed.\nYou bang against the sides of the chute, but it's nothing too damaging.\nFrom the bottom of the chute there's another three foot drop to the laundry basket.\nThat was the farthest three feet of your life!\nThankfully the hamper is full and your landing was soft.\nYou scamper out of the basket, throwing clothes everywhere in the process.\n")
-			curRoom = rooms["Basement Lab"]
-			lookAtRoom()
-		}
-	} else {
-		if userInput[0] == "slide" {
-			fmt.Println("Sliiiiiide to the left *clap* Sliiiiiide to the right.")
-			fmt.Println("You can't remember any more of the dance.")
-		} else if userInput[0] == "jump" {
-			fmt.Println("Jump all you want it's not going to do you any good")
+	for _, p := range curRoom.Passages {
+		if p.Via == "" || hidden(p) {
+			continue
 		}
 
+		for _, w := range userInput[1:] {
+			for _, v := range strings.Fields(p.Via) {
+				if w == v {
+					moveToRoom(p.Via)
+					return
+				}
+			}
+		}
 	}
 
+	if userInput[0] == "slide" {
+		say("sliiiiiide-to-the-left", "Sliiiiiide to the left *clap* Sliiiiiide to the right.")
+		say("you-cant-remember-any", "You can't remember any more of the dance.")
+	} else if userInput[0] == "jump" {
+		say("jump-all-you-want", "Jump all you want it's not going to do you any good")
+	}
 }
 
 // capInput is a helper function to capitalize case insensitive input
@@ -523,13 +556,10 @@ func capInput(input []string) []string {
 
 func quitGame() {
 	// player must confirm they want to quit and stop having fun
-	fmt.Println("Do you want to save your game before you leave? ('y' or 'n')")
-	input := bufio.NewScanner(os.Stdin)
-
+	say("do-you-want-to", "Do you want to save your game before you leave? ('y' or 'n')")
 Goto:
-	for input.Scan() {
-		action := input.Text()
-		s := strings.Fields(action)
+	for in.Scan() {
+		s := strings.Fields(translateAnswer(in.Text()))
 
 		if cap(s) == 0 {
 			continue
@@ -540,15 +570,14 @@ Goto:
 			break Goto // considered convenient
 		case "y":
 			saveGame()
-			os.Exit(0)
+			break Goto
 		default:
-			fmt.Println("Please type 'y' or 'n'.")
+			say("please-type-y-or", "Please type 'y' or 'n'.")
 		}
 	}
 }
 
-func playGame() {
-	openingMessage := fmt.Sprintf(`
+const openingMessage = `
 It was a bright and sunny afternoon. Everything was going fine.
 Your parents were developing new semi-legal technology in their lab,
 and you were watching them. They've told you 100 times to not watch them
@@ -566,238 +595,14 @@ That's the last thing you remember. You open your eyes and seem to be in a
 giant cavern. Everything is so big! Wait...you're so small!
 
 Where are you? How will you fix this? You still have your (tiny!) cell phone
-in your pocket. Should you CALL your parents? No way, they'd save you but
+in your pocket. Should you {verb:call} your parents? No way, they'd save you but
 you'd be in sooooo much trouble.
 
-Is there anywhere you could GO TO? Is there anything you could TAKE to
-help you? HELP! Why don't you try to LOOK around?`)
-
-	fmt.Println(openingMessage)
-
-	input := bufio.NewScanner(os.Stdin)
-	fmt.Print("\n> ")
-
-	for input.Scan() {
-		// split user input at whitespace and match known commands
-		action := input.Text()
-		action = strings.ToLower(action)
-		fmt.Println("")
+Is there anywhere you could {verb:go to}? Is there anything you could {verb:take} to
+help you? {verb:help}! Why don't you try to {verb:look} around?`
 
-		// accept just the room name as input
-		r := strings.Title(action)
-		if _, ok := rooms[r]; ok {
-			moveToRoom(r)
-
-			// This is repetitive, but we must also check if the player returned
-			// to the attic without using the verb 'go'
-			if curRoom.Name == "Attic" {
-				gameOver = haveAllItems()
-			}
-
-			if gameOver {
-				break
-			} else {
-				fmt.Print("\n> ")
-				continue
-			}
-		}
-
-		s := strings.Fields(action)
-
-		//scans the input string for prepositions and deletes them before passing the string to the parser
-		if len(s) > 1 {
-			if !(len(s) == 2 && s[0] == "look" && s[1] == "at") {
-				var tempS strings.Builder
-				for i := 0; i < len(s); i++ {
-					if !(s[i] == "on" || s[i] == "in" || s[i] == "onto" || s[i] == "the" || s[i] == "at" || s[i] == "under" || s[i] == "to" || s[i] == "off" || s[i] == "around") {
-						tempS.WriteString(s[i])
-						tempS.WriteString(" ")
-					}
-				}
-				s = strings.Fields(tempS.String())
-			}
-
-		}
-
-		if cap(s) == 0 {
-			fmt.Print("\n> ")
-			continue
-		}
-
-		switch s[0] {
-		case "look":
-			if len(s) > 1 {
-				if s[1] == strings.ToLower(curRoom.Name) {
-					lookAtRoom()
-					break
-				} else if s[1] == "at" {
-					fmt.Println("What would you like to look at?")
-					break
-				} else {
-					tmp := s[1:]
-					item := strings.Join(tmp, " ")
-					lookAtItem(item)
-				}
-			} else {
-				lookAtRoom()
-			}
-		case "go":
-			// if the word after "go" is "to" ...
-			if len(s) > 1 && s[1] == "to" {
-				// ... but no destination is provided
-				if len(s) < 3 {
-					fmt.Println("Go where?")
-					break
-				} else { // I want to go there!
-					loc := s[2:]
-					loc = capInput(loc)
-					exit := strings.Join(loc, " ")
-					moveToRoom(exit)
-				}
-			} else if len(s) > 1 { // If player says "go" ...
-				loc := s[1:]
-				loc = capInput(loc)
-				exit := strings.Join(loc, " ")
-				moveToRoom(exit)
-			} else {
-				fmt.Println("Go where?")
-			}
-		case "goto":
-			fmt.Println("Go To Statement Considered Harmful!  https://xkcd.com/292")
-		case "take", "grab", "pull", "yank":
-			if len(s) > 1 {
-				tmp := s[1:]
-				item := strings.Join(tmp, " ")
-				takeItem(item)
-			} else {
-				fmt.Println("Take what?")
-			}
-		case "drop":
-			if len(s) > 1 {
-				tmp := s[1:]
-				item := strings.Join(tmp, " ")
-				dropObject(item)
-			} else {
-				fmt.Println("Drop what?")
-			}
-		case "inventory", "mystuff":
-			listInventory()
-		case "shrink":
-			if len(s) > 1 {
-				tmp := s[1:]
-				item := strings.Join(tmp, " ")
-				shrinkObject(item)
-			} else {
-				fmt.Println("Shrink what?")
-			}
-		case "whistle":
-			callTheDog("dog whistle")
-		case "call":
-			callYourParents()
-		case "eat":
-			if len(s) > 1 {
-				tmp := s[1:]
-				item := strings.Join(tmp, " ")
-				eatItem(item)
-			} else {
-				fmt.Println("Eat what?")
-			}
-		case "enter":
-			enterThePassword()
-		case "climb":
-			if len(s) > 1 {
-				tmp := s[1:]
-				item := strings.Join(tmp, " ")
-				climbStuff(item)
-			} else {
-				fmt.Println("Climb what? The corporate ladder?")
-			}
-		case "use":
-			if len(s) > 1 {
-				if s[1] == "umbrella" {
-					useTheUmbrella()
-				} else if s[1] == "whistle" {
-					callTheDog("dog whistle")
-				} else if len(s) > 2 && s[1] == "dog" {
-					callTheDog("dog whistle")
-				} else {
-					fmt.Println("I don't know how to USE that, can you use a more specific action?")
-				}
-			} else {
-				fmt.Println("Use what?")
-			}
-		case "taunt":
-			if len(s) > 1 && s[1] == "eagle" {
-				tauntTheEagle()
-			} else {
-				fmt.Println("There's nobody here to taunt but yourself.")
-			}
-		case "slide":
-			if len(s) > 1 {
-				slideDownJumpIn(s)
-			} else {
-				fmt.Println("Sliiiiiide to the left *clap* Sliiiiiide to the right.")
-				fmt.Println("You can't remember and more of the dance.")
-			}
-		case "jump":
-			if len(s) > 1 {
-				slideDownJumpIn(s)
-			} else {
-				fmt.Println("Jump all you want it's not going to do you any good")
-			}
-		case "cut":
-			if len(s) > 1 {
-				tmp := s[1:]
-				item := strings.Join(tmp, " ")
-				cutStuff(item)
-			} else {
-				fmt.Println("Cut what?")
-			}
-		case "savegame":
-			saveGame()
-		case "quit":
-			quitGame()
-			return
-		case "loadgame":
-			if len(s) > 1 {
-				f := s[1:]
-				g := strings.Join(f, " ")
-				loadGame(g)
-			} else {
-				fmt.Println("Please specify a saved game to load.")
-			}
-		case "help":
-			help()
-		default:
-			fmt.Println("Not a valid command:", action)
-		}
-
-		if curRoom.Name == "Attic" && haveAllItems() {
-			gameOver = true
-		}
-
-		if gameOver {
-			break
-		}
-		fmt.Print("\n> ")
-	}
-	if gameOver && haveAllItems() {
-		winningMessage := fmt.Sprintf(`
-You dump out your backpack and everything tumbles onto the carpet.
-The shampoo, the dirty socks, the candle, the couch stuffing, the copper
-wire, the cornflakes, the screw, the can, the sand, and the software and
-everything else you've picked up all day. Now what?
-
-You grab the notebook and flip through it feverishly looking for
-instructions. EUREKA! You've found them.
-
-You pop the back open on the shrink ray and start dumping stuff in,
-being careful to shake it frequently, just like the notebook says. This is
-one time you will be following instructions!
-
-The shrink ray starts to vibrate and buzz and you see it start to glow purple.
-You plug the software into it and watching it whir to life. You grab it 
--- it's getting really hot now -- and rush over the scorched mirror.
+const winningMessage = `
+The shrink ray is getting really hot now. You rush over to the scorched mirror.
 
 Here goes nothing! You think you hear a car door slam in the driveway.
 
@@ -817,7 +622,10 @@ ROLL CREDITS
 
 
 
- _____________________
+`
+
+// the credits, unless the game is being played with a screen reader
+const thanksMessage = ` _____________________
 < Thanks for playing! >
  ---------------------
         \   ^__^
@@ -827,11 +635,9 @@ ROLL CREDITS
                 ||     ||
 
 
-`)
+`
 
-		fmt.Println(winningMessage)
-	} else {
-		losingMessage := fmt.Sprintf(`You've given up. You can't stand to be this tiny any longer! You call your
+const losingMessage = `You've given up. You can't stand to be this tiny any longer! You call your
 parents who race home from the store. They start lecturing you as they collect
 items from around the house. They had a spare shrink ray the whole time!
 They point it at you and you hear a loud hiss and buzz and your ears pop.
@@ -840,9 +646,400 @@ A purple light surrounds you as you grow back to normal size. What a relief!
 Until your mother grabs you by the ear and throws you in your room.
 You hear the door lock from the outside. You are grounded for eternity.
 
-GAME OVER`)
+GAME OVER`
+
+// parseCommand carries out a single line of player input. It returns false if
+// the player has quit the game.
+func parseCommand(action string) bool {
+	// split user input at whitespace and match known commands
+	action = translateInput(strings.ToLower(action))
+	narrate("")
+
+	if strings.TrimSpace(action) == "repeat" {
+		repeatMessage()
+		return true
+	}
+
+	// keep what the game says, for the player to hear again
+	recording = &strings.Builder{}
+	defer func() {
+		lastMessage = recording.String()
+		recording = nil
+	}()
+
+	// while the player is talking to someone, everything they type is part
+	// of the conversation
+	if talking != nil {
+		continueDialogue(action)
+		return true
+	}
+
+	// the player has been asked whether they really want to eat something
+	if confirming != "" {
+		confirmEating(translateAnswer(action))
+		return true
+	}
+
+	// accept just the room name as input
+	r := strings.Title(action)
+	if _, ok := rooms[r]; ok {
+		moveToRoom(r)
+
+		// This is repetitive, but we must also check if the player returned
+		// to the attic without using the verb 'go'
+		assemble(false)
+
+		passTime()
+		return true
+	}
+
+	s := strings.Fields(action)
+	words := s // before prepositions are removed
+
+	// accept just a direction, like "n" or "up"
+	if d, ok := directions[strings.TrimSpace(action)]; ok {
+		moveToRoom(d)
+		assemble(false)
+
+		passTime()
+		return true
+	}
+
+	//scans the input string for prepositions and deletes them before passing the string to the parser
+	if len(s) > 1 {
+		if !(len(s) == 2 && s[0] == "look" && s[1] == "at") {
+			var tempS strings.Builder
+			for i := 0; i < len(s); i++ {
+				if !(s[i] == "on" || s[i] == "in" || s[i] == "onto" || s[i] == "the" || s[i] == "at" || s[i] == "under" || s[i] == "to" || s[i] == "off" || s[i] == "around") {
+					tempS.WriteString(s[i])
+					tempS.WriteString(" ")
+				}
+			}
+			s = strings.Fields(tempS.String())
+		}
+
+	}
+
+	if cap(s) == 0 {
+		return true
+	}
+
+	// the player can always feel their way back down from something they've
+	// climbed
+	if needsLight[s[0]] && curRoom.Light == Dark && !canSee() && !(len(s) > 1 && s[1] == "down") {
+		say("its-too-dark-to-2", "It's too dark to see what you're doing. You'll need a light.")
+		passTime()
+		return true
+	}
+
+	switch s[0] {
+	case "look":
+		if len(s) > 1 {
+			if words[1] == "in" || words[1] == "inside" {
+				lookInside(strings.Join(s[1:], " "))
+			} else if s[1] == strings.ToLower(curRoom.Name) {
+				lookAtRoom()
+				break
+			} else if s[1] == "at" {
+				say("what-would-you-like", "What would you like to look at?")
+				break
+			} else {
+				tmp := s[1:]
+				item := strings.Join(tmp, " ")
+				lookAtItem(item)
+			}
+		} else {
+			lookAtRoom()
+		}
+	case "go":
+		// "in" is a direction as well as a preposition
+		if len(words) == 2 && words[1] == "in" {
+			moveToRoom("in")
+			break
+		}
+
+		// if the word after "go" is "to" ...
+		if len(s) > 1 && s[1] == "to" {
+			// ... but no destination is provided
+			if len(s) < 3 {
+				say("go-where", "Go where?")
+				break
+			} else { // I want to go there!
+				loc := s[2:]
+				loc = capInput(loc)
+				exit := strings.Join(loc, " ")
+				moveToRoom(exit)
+			}
+		} else if len(s) > 1 { // If player says "go" ...
+			loc := s[1:]
+			loc = capInput(loc)
+			exit := strings.Join(loc, " ")
+			moveToRoom(exit)
+		} else {
+			say("go-where", "Go where?")
+		}
+	case "exits":
+		listExits()
+	case "map":
+		drawMap()
+	case "light":
+		if len(s) > 1 {
+			lightItem(strings.Join(s[1:], " "))
+		} else {
+			say("light-what", "Light what?")
+		}
+	case "extinguish", "snuff", "blow":
+		if len(s) > 1 && s[1] == "out" {
+			s = s[1:]
+		}
+
+		if len(s) > 1 {
+			putOut(strings.Join(s[1:], " "))
+		} else {
+			say("put-out-what", "Put out what?")
+		}
+	case "goto":
+		say("go-to-statement-considered", "Go To Statement Considered Harmful!  https://xkcd.com/292")
+	case "take", "grab", "pull", "yank":
+		if len(s) > 1 {
+			if item, c, ok := splitAt(s[1:], "from"); ok {
+				takeFrom(item, c)
+				break
+			}
+			tmp := s[1:]
+			item := strings.Join(tmp, " ")
+			takeItem(item)
+		} else {
+			say("take-what", "Take what?")
+		}
+	case "put":
+		if len(s) > 2 && s[1] == "out" {
+			putOut(strings.Join(s[2:], " "))
+		} else if item, c, ok := splitAt(words[1:], "in", "into", "inside"); ok && item != "" && c != "" {
+			putItem(strings.TrimPrefix(item, "the "), strings.TrimPrefix(c, "the "))
+		} else {
+			say("put-what-in-what", "Put what in what? Try: put <item> in <container>")
+		}
+	case "open":
+		if len(s) > 1 {
+			openContainer(strings.Join(s[1:], " "))
+		} else {
+			say("open-what", "Open what?")
+		}
+	case "close", "shut":
+		if len(s) > 1 {
+			closeContainer(strings.Join(s[1:], " "))
+		} else {
+			say("close-what", "Close what?")
+		}
+	case "lock", "unlock":
+		if len(s) > 1 {
+			lockContainer(strings.Join(s[1:], " "), s[0] == "lock")
+		} else {
+			say("verb-what", "%s what?", strings.Title(s[0]))
+		}
+	case "drop":
+		if len(s) > 1 {
+			tmp := s[1:]
+			item := strings.Join(tmp, " ")
+			dropObject(item)
+		} else {
+			say("drop-what", "Drop what?")
+		}
+	case "inventory", "mystuff":
+		listInventory()
+	case "shrink":
+		if len(s) > 1 {
+			tmp := s[1:]
+			item := strings.Join(tmp, " ")
+			shrinkObject(item)
+		} else {
+			say("shrink-what", "Shrink what?")
+		}
+	case "grow", "enlarge":
+		if len(s) > 1 {
+			growObject(strings.Join(s[1:], " "))
+		} else {
+			say("grow-what", "Grow what?")
+		}
+	case "whistle":
+		callTheDog("dog whistle")
+	case "call":
+		talkTo("parents")
+	case "talk", "speak":
+		if len(s) > 1 {
+			talkTo(strings.Join(s[1:], " "))
+		} else {
+			say("talk-to-whom", "Talk to whom?")
+		}
+	case "combine", "mix":
+		if len(s) > 1 {
+			combineItems(strings.Join(s[1:], " "))
+		} else {
+			say("combine-what", "Combine what?")
+		}
+	case "eat":
+		if len(s) > 1 {
+			tmp := s[1:]
+			item := strings.Join(tmp, " ")
+			eatItem(item)
+		} else {
+			say("eat-what", "Eat what?")
+		}
+	case "rest", "sit", "sleep":
+		rest()
+	case "enter":
+		enterThePassword()
+	case "climb":
+		if len(s) > 1 {
+			tmp := s[1:]
+			item := strings.Join(tmp, " ")
+			climbStuff(item)
+		} else {
+			say("climb-what-the-corporate", "Climb what? The corporate ladder?")
+		}
+	case "use":
+		if len(s) > 1 {
+			if s[1] == "umbrella" {
+				useTheUmbrella()
+			} else if s[1] == "whistle" {
+				callTheDog("dog whistle")
+			} else if len(s) > 2 && s[1] == "dog" {
+				callTheDog("dog whistle")
+			} else if reactToAll("use " + strings.Join(s[1:], " ")) {
+				break
+			} else {
+				say("i-dont-know-how", "I don't know how to {verb:use} that, can you use a more specific action?")
+			}
+		} else {
+			say("use-what", "Use what?")
+		}
+	case "taunt":
+		if len(s) > 1 {
+			tauntTheEagle(strings.Join(s[1:], " "))
+		} else {
+			say("theres-nobody-here-to", "There's nobody here to taunt but yourself.")
+		}
+	case "throw":
+		if len(s) > 1 {
+			tmp := s[1:]
+			item := strings.Join(tmp, " ")
+			if _, ok := inventory[item]; !ok {
+				say("is-not-in-your", "%s is not in your backpack.", item)
+			} else if !reactToAll("use " + item) {
+				say("theres-nothing-here-worth", "There's nothing here worth throwing that at.")
+			}
+		} else {
+			say("throw-what", "Throw what?")
+		}
+	case "slide":
+		if len(s) > 1 {
+			slideDownJumpIn(s)
+		} else {
+			say("sliiiiiide-to-the-left", "Sliiiiiide to the left *clap* Sliiiiiide to the right.")
+			say("you-cant-remember-and", "You can't remember and more of the dance.")
+		}
+	case "jump":
+		if len(s) > 1 {
+			slideDownJumpIn(s)
+		} else {
+			say("jump-all-you-want", "Jump all you want it's not going to do you any good")
+		}
+	case "cut":
+		if len(s) > 1 {
+			tmp := s[1:]
+			item := strings.Join(tmp, " ")
+			cutStuff(item)
+		} else {
+			say("cut-what", "Cut what?")
+		}
+	case "savegame":
+		saveGame()
+	case "quit":
+		quitGame()
+		return false
+	case "loadgame":
+		if len(s) > 1 {
+			f := s[1:]
+			g := strings.Join(f, " ")
+			loadGame(g)
+		} else {
+			say("please-specify-a-saved", "Please specify a saved game to load.")
+		}
+	case "verbose", "brief", "superbrief":
+		setVerbosity(s[0])
+	case "help":
+		help()
+	default:
+		// NPCs define their own verbs, like "pet dog"
+		if len(s) < 2 || !interactWithNPC(s[0], strings.Join(s[1:], " ")) {
+			say("not-a-valid-command", "Not a valid command: %s", action)
+			return true
+		}
+	}
+
+	assemble(false)
+
+	if !takesNoTime[s[0]] {
+		passTime()
+	}
+	return true
+}
+
+// passTime lets the rest of the house take a turn after the player has taken
+// theirs, so candles burn down and NPCs wander. Commands which only tell the
+// player about the game take no time.
+func passTime() {
+	turns++
+	burnLights()
+	moveNPCs()
+	advanceEncounters()
+	statusChanged()
+}
 
-		fmt.Println(losingMessage)
+// endGame prints the ending the player has earned
+func endGame() {
+	if gameOver && haveAllItems() {
+		narrate(strings.TrimSuffix(text("winning", winningMessage), "\n"))
+		if accessible {
+			say("thanks-for-playing", "Thanks for playing!")
+		} else {
+			narrate(text("thanks", thanksMessage))
+		}
+	} else {
+		narrate(text("losing", losingMessage))
+	}
+}
+
+func playGame() {
+	narrate(text("opening", openingMessage))
+	fmt.Fprint(out, "\n> ")
+	noteNameable()
+
+	if devMode {
+		stop := watchRooms(func() { fmt.Fprint(out, "\n> ") })
+		defer stop()
+	}
+
+	for in.Scan() {
+		// the rooms can only be reloaded between commands
+		engineMu.Lock()
+		playing := parseCommand(in.Text())
+		if playing && !gameOver {
+			fmt.Fprint(out, "\n> ")
+		}
+		noteNameable()
+		engineMu.Unlock()
+
+		if !playing {
+			return
+		}
+		if gameOver {
+			break
+		}
 	}
 
+	engineMu.Lock()
+	defer engineMu.Unlock()
+	endGame()
 }
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// a command sent by a program playing the game, one per line. A command with
+// Reset starts a new game instead of playing.
+type ProtocolCommand struct {
+	Command string         `json:"command"`
+	Reset   *ProtocolReset `json:"reset,omitempty"`
+}
+
+// how a new game is played: the seed deciding what the NPCs get up to, and
+// what progress is worth. Without rewards, the ones already in use are kept.
+type ProtocolReset struct {
+	Seed    int64    `json:"seed"`
+	Rewards *Rewards `json:"rewards,omitempty"`
+}
+
+// the response to each command, one per line
+type ProtocolResponse struct {
+	Observation
+	Actions []string `json:"actions"` // commands that currently mean something
+	Reward  float64  `json:"reward"`
+	Done    bool     `json:"done"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// playProtocol plays the game with a program instead of a person. Each line
+// read from 'r' is a JSON ProtocolCommand and each line written to 'w' is a
+// JSON ProtocolResponse. The text of the game is the same as a person sees.
+// A game with seed 0 is started straight away, and a reset starts another,
+// even once the game is over.
+func playProtocol(r io.Reader, w io.Writer) {
+	enc := json.NewEncoder(w)
+	lines := bufio.NewScanner(r)
+
+	env := newEnv(DefaultRewards)
+	enc.Encode(ProtocolResponse{Observation: env.Reset(0), Actions: env.ValidActions()})
+
+	for lines.Scan() {
+		var c ProtocolCommand
+		if e := json.Unmarshal(lines.Bytes(), &c); e != nil {
+			enc.Encode(ProtocolResponse{
+				Observation: env.Observe(),
+				Actions:     env.ValidActions(),
+				Error:       "expected {\"command\": \"...\"}",
+			})
+			continue
+		}
+
+		if c.Reset != nil {
+			if c.Reset.Rewards != nil {
+				env.Rewards = *c.Reset.Rewards
+			}
+			enc.Encode(ProtocolResponse{Observation: env.Reset(c.Reset.Seed), Actions: env.ValidActions()})
+			continue
+		}
+
+		if f := strings.Fields(strings.ToLower(c.Command)); len(f) > 0 && f[0] == "quit" {
+			o := env.Observe()
+			o.Text = []string{"Goodbye!"}
+			enc.Encode(ProtocolResponse{Observation: o, Actions: []string{}, Done: true})
+			return
+		}
+
+		o, reward, done, _ := env.Step(c.Command)
+		enc.Encode(ProtocolResponse{Observation: o, Actions: env.ValidActions(), Reward: reward, Done: done})
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestProtocolResetTakesSeedAndRewards(t *testing.T) {
+	in := strings.Join([]string{
+		`{"command": "look"}`,
+		`{"reset": {"seed": 9, "rewards": {"step": -0.5}}}`,
+		`{"command": "look"}`,
+	}, "\n")
+
+	var w bytes.Buffer
+	playProtocol(strings.NewReader(in), &w)
+
+	var got []ProtocolResponse
+	for _, l := range strings.Split(strings.TrimSpace(w.String()), "\n") {
+		var r ProtocolResponse
+		if e := json.Unmarshal([]byte(l), &r); e != nil {
+			t.Fatal(e)
+		}
+		got = append(got, r)
+	}
+
+	if len(got) != 4 {
+		t.Fatalf("got %d responses, want 4", len(got))
+	}
+	if got[1].Reward != 0 {
+		t.Errorf("looking with the default rewards was worth %v", got[1].Reward)
+	}
+	if got[2].Room != "Attic" || len(got[2].Actions) == 0 {
+		t.Errorf("the reset didn't start a new game: %+v", got[2])
+	}
+	if got[3].Reward != -0.5 {
+		t.Errorf("looking after the reset was worth %v, want -0.5", got[3].Reward)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"time"
+)
+
+var devMode bool                   // rooms are reloaded as their files change
+var roomFiles map[string]fileStamp // the room files as they were last loaded
+
+// when a file was last changed, and how big it was
+type fileStamp struct {
+	modified time.Time
+	size     int64
+}
+
+// stampRooms returns when each file in the rooms directory was last changed
+func stampRooms() map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+	files, e := ioutil.ReadDir("rooms")
+	if e != nil {
+		return stamps
+	}
+
+	for _, f := range files {
+		stamps[f.Name()] = fileStamp{f.ModTime(), f.Size()}
+	}
+
+	return stamps
+}
+
+// watchRooms starts reloading the rooms in the background as their files
+// change, from the rooms as they are now, without waiting for the player to
+// type anything. After each reload 'shown' is called, if it isn't nil, to
+// bring the player's screen up to date. Commands must hold engineMu, so the
+// rooms don't change under them, and anything else reading the game must use
+// what the engine left it, like the names noteNameable keeps for completing.
+// The returned function stops the watching.
+func watchRooms(shown func()) func() {
+	roomFiles = stampRooms()
+
+	tick := time.NewTicker(time.Second)
+	go func() {
+		for range tick.C {
+			engineMu.Lock()
+			if reloadRooms() {
+				noteNameable()
+				if shown != nil {
+					shown()
+				}
+			}
+			engineMu.Unlock()
+		}
+	}()
+
+	return tick.Stop
+}
+
+// reloadRooms loads the rooms again if any of their files have changed since
+// they were last loaded, keeping the game in progress. Anything wrong with
+// the files is reported, and the game carries on as it was. It reports
+// whether the files had changed.
+func reloadRooms() bool {
+	stamps := stampRooms()
+	if reflect.DeepEqual(stamps, roomFiles) {
+		return false
+	}
+	roomFiles = stamps
+
+	m, _, e := readRooms("rooms")
+	if e != nil {
+		narrate(fmt.Sprintf("\nCouldn't reload the rooms: %v", e))
+		return true
+	}
+
+	problems := checkRooms(m)
+	for _, name := range roomsInUse() {
+		if _, ok := m[name]; !ok {
+			problems = append(problems, fmt.Errorf("%s is gone, but the game is using it", name))
+		}
+	}
+	if len(problems) > 0 {
+		narrate("\nCouldn't reload the rooms:")
+		for _, p := range problems {
+			narrate(fmt.Sprintf("     %v", p))
+		}
+		return true
+	}
+
+	if e := localizeRooms(m); e != nil {
+		narrate(fmt.Sprintf("\nCouldn't reload the rooms: %v", e))
+		return true
+	}
+	defs := copyRooms(m)
+	changed := !reflect.DeepEqual(world[curRoom.Name], defs[curRoom.Name])
+
+	// where the player has put things, before any room changes
+	moved := make(map[string]bool)
+	for name := range catalog {
+		moved[name] = movedByPlayer(name)
+	}
+
+	for name, r := range m {
+		old, ok := rooms[name]
+		was, defined := world[name]
+		if !ok || !defined {
+			rooms[name] = r
+			continue
+		}
+
+		items := mergeItems(old.Items, was.Items, r.Items, moved)
+		mergeFields(reflect.ValueOf(old).Elem(), reflect.ValueOf(was).Elem(), reflect.ValueOf(r).Elem())
+		old.Items = items
+	}
+	for name := range rooms {
+		if _, ok := m[name]; !ok {
+			delete(rooms, name)
+		}
+	}
+
+	roomAliases = make(map[string]string)
+	for _, r := range rooms {
+		if r.Alias != "" {
+			roomAliases[r.Alias] = r.Name
+		}
+	}
+
+	world = defs
+	for _, r := range world {
+		addToCatalog(r.Items)
+	}
+
+	narrate(fmt.Sprintf("\nReloaded %d rooms.", len(m)))
+
+	// the player sees the room they're in as it is now
+	if changed {
+		narrate("")
+		lookAtRoom()
+		exitsChanged()
+	}
+
+	return true
+}
+
+// roomsInUse names the rooms the player, the NPCs and the encounters are in
+func roomsInUse() []string {
+	names := []string{curRoom.Name}
+	for _, n := range npcs {
+		names = append(names, n.Room, n.Home)
+	}
+	for _, enc := range encounters {
+		names = append(names, enc.Room)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// movedByPlayer reports whether the player is carrying the item called
+// 'name', or has left it in a room which didn't have it to begin with
+func movedByPlayer(name string) bool {
+	if anywhere(inventory, name) {
+		return true
+	}
+
+	for room, r := range rooms {
+		if was, ok := world[room]; ok && anywhere(r.Items, name) && !anywhere(was.Items, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mergeItems returns the items a room should have now that its definition
+// has changed from 'was' to 'now', given it has 'items' in the game. Items
+// the player has moved, listed in 'moved', stay where the player put them,
+// even if the new definitions put them in another room, and items the player
+// hasn't touched take their new definition.
+func mergeItems(items map[string]*Item, was map[string]*Item, now map[string]*Item, moved map[string]bool) map[string]*Item {
+	merged := make(map[string]*Item)
+	for name, item := range now {
+		old, inGame := items[name]
+		def, defined := was[name]
+
+		switch {
+		case inGame && defined:
+			mergeFields(reflect.ValueOf(old).Elem(), reflect.ValueOf(def).Elem(), reflect.ValueOf(item).Elem())
+			merged[name] = old
+		case inGame:
+			merged[name] = old
+		case !defined && !moved[name]:
+			merged[name] = item
+		}
+	}
+
+	// what the player has left here stays
+	for name, item := range items {
+		if _, defined := was[name]; !defined {
+			merged[name] = item
+		}
+	}
+
+	return merged
+}
+
+// mergeFields updates 'old', which was defined as 'was', to the new definition
+// 'now', except for the fields the game has changed since
+func mergeFields(old reflect.Value, was reflect.Value, now reflect.Value) {
+	for i := 0; i < old.NumField(); i++ {
+		if reflect.DeepEqual(old.Field(i).Interface(), was.Field(i).Interface()) {
+			old.Field(i).Set(now.Field(i))
+		}
+	}
+}
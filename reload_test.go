@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeItemsKeepsWhatThePlayerMoved(t *testing.T) {
+	was := map[string]*Item{"spoon": {Name: "spoon", Size: 2}, "fork": {Name: "fork", Size: 2}}
+	now := map[string]*Item{"spoon": {Name: "spoon", Size: 3}, "fork": {Name: "fork", Size: 2}, "knife": {Name: "knife"}, "cup": {Name: "cup"}}
+
+	// the player took the fork and left a sock, and has the cup from
+	// another room
+	spoon := &Item{Name: "spoon", Size: 2}
+	sock := &Item{Name: "sock"}
+	items := map[string]*Item{"spoon": spoon, "sock": sock}
+
+	merged := mergeItems(items, was, now, map[string]bool{"cup": true})
+
+	if got, want := sortedNames(merged), []string{"knife", "sock", "spoon"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("merged items are %v, want %v", got, want)
+	}
+	if merged["spoon"] != spoon || spoon.Size != 3 {
+		t.Errorf("the spoon wasn't updated in place: %+v", merged["spoon"])
+	}
+	if merged["sock"] != sock {
+		t.Error("the sock the player left was replaced")
+	}
+}
+
+func TestMergeFieldsKeepsWhatTheGameChanged(t *testing.T) {
+	old := &Item{Name: "candle", Description: "A candle.", Burns: 12, Lit: true}
+	was := &Item{Name: "candle", Description: "A candle.", Burns: 40}
+	now := &Item{Name: "candle", Description: "A tall candle.", Burns: 60}
+
+	mergeFields(reflect.ValueOf(old).Elem(), reflect.ValueOf(was).Elem(), reflect.ValueOf(now).Elem())
+
+	if old.Description != "A tall candle." {
+		t.Errorf("the description wasn't updated: %q", old.Description)
+	}
+	if old.Burns != 12 || !old.Lit {
+		t.Errorf("the burning candle was reset: %+v", old)
+	}
+}
+
+// sortedNames returns the names of 'items', sorted
+func sortedNames(items map[string]*Item) []string {
+	names := make(map[string]bool)
+	for name := range items {
+		names[name] = true
+	}
+
+	return sortedKeys(names)
+}
@@ -0,0 +1,173 @@
+package main
+
+// how big things are, from smallest to largest. The player has been shrunk
+// to Tiny, and can pick up things up to one size bigger than themselves.
+const (
+	Microscopic = iota
+	Tiny
+	Small
+	Medium
+	Large
+	Huge
+)
+
+var sizeNames = []string{"microscopic", "tiny", "small", "medium", "large", "huge"}
+
+// sizeName names size 'n' in the player's language
+func sizeName(n int) string {
+	return text("size-"+sizeNames[n], sizeNames[n])
+}
+
+var shrunk int // how many steps the player has shrunk themself since the accident
+
+// playerSize is how big the player is now
+func playerSize() int {
+	return Tiny - shrunk
+}
+
+// size is how big item 'i' is now
+func (i *Item) size() int {
+	return i.Size - i.Shrunk
+}
+
+// weight is how heavy item 'i' is now. Every step smaller halves its weight.
+func (i *Item) weight() int {
+	w := i.Weight >> uint(i.Shrunk)
+	if w < 1 && i.Weight > 0 {
+		w = 1
+	}
+
+	return w
+}
+
+// tooBig reports whether item 'i' is too big for the player to pick up
+func (i *Item) tooBig() bool {
+	return i.size() > playerSize()+1
+}
+
+// lookAtYourself describes how big the player is
+func lookAtYourself() {
+	m := text("you-are", "You are %s. ", sizeName(playerSize()))
+	if playerSize() == Microscopic {
+		m += phrase("dust-motes-drift-past", "Dust motes drift past you like boulders.")
+	} else {
+		m += phrase("about-the-size-of", "About the size of a corn flake, really.")
+	}
+	narrate(m)
+	say("you-can-pick-up", "You can pick up things that are %s or smaller.", sizeName(playerSize()+1))
+	narrate(feeling())
+}
+
+// shrinkObject zaps 'item' with the shrink ray, making it one size smaller
+func shrinkObject(item string) {
+	if _, ok := inventory["shrink ray"]; !ok {
+		say("you-need-the-shrink", "You need the shrink ray to shrink things.")
+		return
+	}
+
+	if item == "me" || item == "myself" || item == "yourself" {
+		if playerSize() == Microscopic {
+			say("any-smaller-and-youd", "Any smaller and you'd disappear altogether.")
+			return
+		}
+		say("you-turn-the-shrink", "You turn the shrink ray around and squeeze your eyes shut.\nSHRINKING!")
+		shrunk++
+		lookAtYourself()
+		return
+	}
+
+	if item == "shrink ray" {
+		say("you-cant-shrink-the", "You can't shrink the shrink ray")
+		return
+	}
+
+	where, ok := findItem(inventory, item)
+	carried := ok
+	if !ok {
+		where, ok = findItem(curRoom.Items, item)
+	}
+
+	if !ok || !where[item].Discovered {
+		say("there-is-no-in-2", "There is no '%s' in this room to shrink!", item)
+		return
+	}
+
+	val := where[item]
+	if val.IsFeature {
+		say("you-cant-shrink-this", "You can't shrink this. Mom and Dad might notice!")
+		return
+	}
+
+	if val.size() == Microscopic {
+		say("i-dont-think-that", "I don't think that can get any smaller. Did you try to just {verb:take} it?")
+		return
+	}
+
+	say("shrinking", "SHRINKING!")
+	val.Shrunk++
+	say("the-is-now", "The %s is %s now.", item, sizeName(val.size()))
+
+	if !val.tooBig() && !carried {
+		say("this-item-is-now", "This item is now small enough to collect. You can {verb:take} it now.")
+	}
+}
+
+// growObject zaps 'item' with the shrink ray's grow mode, making it one size
+// bigger, but never bigger than it was to start with
+func growObject(item string) {
+	if _, ok := inventory["shrink ray"]; !ok {
+		say("you-need-the-shrink-2", "You need the shrink ray to grow things.")
+		return
+	}
+
+	if item == "me" || item == "myself" || item == "yourself" {
+		if shrunk == 0 {
+			say("the-shrink-ray-sputters", "The shrink ray sputters and fizzles. The de-shrink function is what's\nbroken, remember? You'll have to fix it to get back to normal.")
+			return
+		}
+		say("you-turn-the-shrink-2", "You turn the shrink ray around and squeeze your eyes shut.\nGROWING!")
+		shrunk--
+		lookAtYourself()
+		return
+	}
+
+	where, ok := findItem(inventory, item)
+	carried := ok
+	if !ok {
+		where, ok = findItem(curRoom.Items, item)
+	}
+
+	if !ok || !where[item].Discovered {
+		say("there-is-no-in-3", "There is no '%s' in this room to grow!", item)
+		return
+	}
+
+	val := where[item]
+	if val.Shrunk == 0 {
+		say("the-shrink-ray-can", "The shrink ray can only grow things back to how big they were.\nThe %s is already %s.", item, sizeName(val.size()))
+		return
+	}
+
+	if val.size() >= playerSize()+1 && carried {
+		say("if-the-grew-any", "If the %s grew any bigger you wouldn't be able to carry it.\n{verb:drop} it first.", item)
+		return
+	}
+
+	val.Shrunk--
+	if carried {
+		// the bigger item has to fit in the backpack as if it were being
+		// picked up again
+		delete(where, item)
+		fits := canCarry(inventory, val)
+		where[item] = val
+
+		if !fits {
+			val.Shrunk++
+			say("the-shrink-ray-wont", "The shrink ray won't grow the %s while it's too heavy for you to carry.", item)
+			return
+		}
+	}
+
+	say("growing", "GROWING!")
+	say("the-is-now", "The %s is %s now.", item, sizeName(val.size()))
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestGrowingStopsAtWhatThePlayerCanCarry(t *testing.T) {
+	playIn("Kitchen")
+	rock := &Item{Name: "rock", Size: 2, Weight: 64, Shrunk: 2, Discovered: true}
+	inventory["rock"] = rock
+	parseCommand("grow rock")
+	if rock.Shrunk != 2 {
+		t.Errorf("the rock grew to weigh %d, more than the player can carry", rock.weight())
+	}
+
+	rock.Weight = 8
+	parseCommand("grow rock")
+	if rock.Shrunk != 1 {
+		t.Error("the rock didn't grow when the player could still carry it")
+	}
+
+	// in the room, it can grow as big as it likes
+	delete(inventory, "rock")
+	rock.Weight = 64
+	curRoom.Items["rock"] = rock
+	parseCommand("grow rock")
+	if rock.Shrunk != 0 {
+		t.Error("the rock in the room didn't grow")
+	}
+}
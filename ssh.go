@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
+)
+
+// SSH usernames double as directory names, so keep them simple
+var validPlayerName = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// achievements a player can earn, and what earns them
+var achievements = []struct {
+	Name   string
+	Earned func(s *Session) bool
+}{
+	{"Explorer", func(s *Session) bool {
+		for _, r := range s.Game.Rooms {
+			if !r.Visited {
+				return false
+			}
+		}
+		return true
+	}},
+	{"Collector", func(s *Session) bool {
+		return score(s.Game.Inventory) == len(winningItems)
+	}},
+	{"Back to Normal", func(s *Session) bool {
+		return s.GameOver && score(s.Game.Inventory) == len(winningItems)
+	}},
+	{"Grounded for Eternity", func(s *Session) bool {
+		return s.GameOver && score(s.Game.Inventory) != len(winningItems)
+	}},
+}
+
+// the files belonging to a single SSH player
+type PlayerFiles struct {
+	dir string
+}
+
+func (p PlayerFiles) save() string         { return filepath.Join(p.dir, "save.json") }
+func (p PlayerFiles) achievements() string { return filepath.Join(p.dir, "achievements.json") }
+func (p PlayerFiles) key() string          { return filepath.Join(p.dir, "authorized_key") }
+
+// loadHostKey reads the server's host key from 'dir', generating and storing
+// a new one on first run.
+func loadHostKey(dir string) ssh.Signer {
+	f := filepath.Join(dir, "ssh_host_ed25519_key")
+
+	b, e := ioutil.ReadFile(f)
+	if os.IsNotExist(e) {
+		_, priv, e := ed25519.GenerateKey(rand.Reader)
+		if e != nil {
+			log.Fatal(e)
+		}
+
+		block, e := ssh.MarshalPrivateKey(priv, "")
+		if e != nil {
+			log.Fatal(e)
+		}
+
+		b = pem.EncodeToMemory(block)
+		if e := ioutil.WriteFile(f, b, 0600); e != nil {
+			log.Fatal(e)
+		}
+		log.Printf("generated new host key %s", f)
+	} else if e != nil {
+		log.Fatal(e)
+	}
+
+	signer, e := ssh.ParsePrivateKey(b)
+	if e != nil {
+		log.Fatal(e)
+	}
+
+	return signer
+}
+
+// checkPlayerKey identifies players by their SSH username. The first public
+// key a player connects with is remembered, and only that key may be used to
+// play as them afterwards.
+func checkPlayerKey(dir string, user string, key ssh.PublicKey) error {
+	if !validPlayerName.MatchString(user) {
+		return errors.New("invalid player name")
+	}
+
+	p := PlayerFiles{filepath.Join(dir, "players", user)}
+	if e := os.MkdirAll(p.dir, 0755); e != nil {
+		return e
+	}
+
+	b, e := ioutil.ReadFile(p.key())
+	if os.IsNotExist(e) {
+		return ioutil.WriteFile(p.key(), ssh.MarshalAuthorizedKey(key), 0600)
+	} else if e != nil {
+		return e
+	}
+
+	known, _, _, _, e := ssh.ParseAuthorizedKey(b)
+	if e != nil {
+		return e
+	}
+
+	if !bytes.Equal(known.Marshal(), key.Marshal()) {
+		return errors.New("key does not match player")
+	}
+
+	return nil
+}
+
+// serveSSH plays the game over SSH on 'addr' until the listener fails.
+// Host keys and player files are kept in 'dir'. If 'shared' is true, all the
+// players share one house instead of playing their own games.
+func serveSSH(addr string, dir string, shared bool) {
+	if e := os.MkdirAll(dir, 0755); e != nil {
+		log.Fatal(e)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(c ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			return nil, checkPlayerKey(dir, c.User(), key)
+		},
+	}
+	config.AddHostKey(loadHostKey(dir))
+
+	l, e := net.Listen("tcp", addr)
+	if e != nil {
+		log.Fatal(e)
+	}
+
+	log.Printf("serving the game over ssh on %s", addr)
+
+	for {
+		conn, e := l.Accept()
+		if e != nil {
+			log.Fatal(e)
+		}
+
+		go func() {
+			sc, chans, reqs, e := ssh.NewServerConn(conn, config)
+			if e != nil {
+				log.Print(e)
+				return
+			}
+			defer sc.Close()
+			go ssh.DiscardRequests(reqs)
+
+			for nc := range chans {
+				if nc.ChannelType() != "session" {
+					nc.Reject(ssh.UnknownChannelType, "only sessions are supported")
+					continue
+				}
+
+				ch, requests, e := nc.Accept()
+				if e != nil {
+					log.Print(e)
+					continue
+				}
+
+				p := PlayerFiles{filepath.Join(dir, "players", sc.User())}
+				go playSSH(ch, requests, sc.User(), p, shared)
+			}
+		}()
+	}
+}
+
+// playSSH runs a game for the player 'user' on an SSH channel
+func playSSH(ch ssh.Channel, requests <-chan *ssh.Request, user string, p PlayerFiles, shared bool) {
+	defer ch.Close()
+
+	t := term.NewTerminal(ch, "> ")
+
+	go func() {
+		for req := range requests {
+			switch req.Type {
+			case "pty-req":
+				var pty struct {
+					Term                      string
+					Cols, Rows, Width, Height uint32
+					Modes                     string
+				}
+				if ssh.Unmarshal(req.Payload, &pty) == nil {
+					t.SetSize(int(pty.Cols), int(pty.Rows))
+				}
+				req.Reply(true, nil)
+			case "window-change":
+				var win struct {
+					Cols, Rows, Width, Height uint32
+				}
+				if ssh.Unmarshal(req.Payload, &win) == nil {
+					t.SetSize(int(win.Cols), int(win.Rows))
+				}
+			case "shell":
+				req.Reply(true, nil)
+			default:
+				req.Reply(false, nil)
+			}
+		}
+	}()
+
+	if shared {
+		playShared(t, user)
+		return
+	}
+
+	s := &Session{ID: user, LastUsed: time.Now()}
+	if loadPlayerGame(p, s) {
+		fmt.Fprint(t, text("welcome-back", "Welcome back, %s!\n", user))
+		printLines(t, playTurn(s, "look").Output)
+	} else {
+		s.Game = newGame()
+		fmt.Fprintln(t, render(text("opening", openingMessage), ColorText))
+	}
+
+	earned := loadAchievements(p)
+
+	for !s.GameOver {
+		fmt.Fprintln(t)
+		action, e := t.ReadLine()
+		if e != nil {
+			savePlayerGame(p, s)
+			return
+		}
+
+		switch f := strings.Fields(strings.ToLower(action)); {
+		case len(f) == 0:
+			continue
+		case f[0] == "savegame":
+			savePlayerGame(p, s)
+			fmt.Fprintln(t, text("saved-your-game", "Saved your game."))
+		case f[0] == "loadgame":
+			if !loadPlayerGame(p, s) {
+				fmt.Fprintln(t, text("you-dont-have-a-2", "You don't have a saved game."))
+				continue
+			}
+			printLines(t, playTurn(s, "look").Output)
+		case f[0] == "quit":
+			savePlayerGame(p, s)
+			fmt.Fprintln(t, text("saved-your-game-see", "Saved your game. See you next time!"))
+			return
+		case f[0] == "achievements":
+			listAchievements(t, earned)
+		default:
+			printLines(t, playTurn(s, action).Output)
+		}
+
+		for _, a := range achievements {
+			if _, ok := earned[a.Name]; !ok && a.Earned(s) {
+				earned[a.Name] = time.Now()
+				fmt.Fprint(t, text("achievement-unlocked", "\n*** Achievement unlocked: %s ***\n", a.Name))
+			}
+		}
+		saveAchievements(p, earned)
+	}
+
+	// a finished game cannot be resumed
+	os.Remove(p.save())
+}
+
+// playShared runs the game for the player 'user' in the shared house
+func playShared(t *term.Terminal, user string) {
+	p, e := joinHouse(user, t)
+	if e != nil {
+		fmt.Fprintln(t, e)
+		return
+	}
+	defer leaveHouse(p)
+
+	fmt.Fprintln(t, render(text("opening", openingMessage), ColorText))
+	printLines(t, who())
+
+	for !p.s.GameOver {
+		fmt.Fprintln(t)
+		action, e := t.ReadLine()
+		if e != nil {
+			return
+		}
+
+		if winner := houseWinner(); winner != "" {
+			fmt.Fprint(t, text("has-already-fixed-the", "%s has already fixed the shrink ray. The game is over!\n", winner))
+			return
+		}
+
+		if lines, ok := sharedCommand(p, action); ok {
+			printLines(t, lines)
+			continue
+		}
+
+		if f := strings.Fields(strings.ToLower(action)); len(f) > 0 && f[0] == "quit" {
+			fmt.Fprintln(t, text("see-you-next-time", "See you next time!"))
+			return
+		}
+
+		printLines(t, playSharedTurn(p, action))
+	}
+}
+
+// printLines writes each line of game output to the terminal 't'
+func printLines(t *term.Terminal, lines []string) {
+	for _, l := range lines {
+		fmt.Fprintln(t, render(l, ColorText))
+	}
+}
+
+// loadPlayerGame restores the player's saved game into session 's'
+func loadPlayerGame(p PlayerFiles, s *Session) bool {
+	b, e := ioutil.ReadFile(p.save())
+	if e != nil {
+		return false
+	}
+
+	var g Game
+	if json.Unmarshal(b, &g) != nil {
+		log.Printf("ignoring unreadable save %s", p.save())
+		return false
+	}
+	if e := checkGame(g); e != nil {
+		log.Printf("ignoring unreadable save %s: %v", p.save(), e)
+		return false
+	}
+
+	engineMu.Lock()
+	s.Game = g
+	engineMu.Unlock()
+
+	return true
+}
+
+// savePlayerGame stores the game in session 's' as the player's saved game
+func savePlayerGame(p PlayerFiles, s *Session) {
+	engineMu.Lock()
+	b, e := json.MarshalIndent(s.Game, "", " ")
+	engineMu.Unlock()
+
+	if e != nil {
+		log.Print(e)
+		return
+	}
+
+	if e := ioutil.WriteFile(p.save(), b, 0644); e != nil {
+		log.Print(e)
+	}
+}
+
+// loadAchievements reads the achievements the player has already earned
+func loadAchievements(p PlayerFiles) map[string]time.Time {
+	earned := make(map[string]time.Time)
+
+	if b, e := ioutil.ReadFile(p.achievements()); e == nil {
+		json.Unmarshal(b, &earned)
+	}
+
+	return earned
+}
+
+// saveAchievements stores the achievements the player has earned
+func saveAchievements(p PlayerFiles, earned map[string]time.Time) {
+	b, e := json.MarshalIndent(earned, "", " ")
+	if e != nil {
+		log.Print(e)
+		return
+	}
+
+	if e := ioutil.WriteFile(p.achievements(), b, 0644); e != nil {
+		log.Print(e)
+	}
+}
+
+// listAchievements prints the achievements the player has earned so far
+func listAchievements(t *term.Terminal, earned map[string]time.Time) {
+	if len(earned) == 0 {
+		fmt.Fprintln(t, text("you-havent-earned-any", "You haven't earned any achievements yet."))
+		return
+	}
+
+	var names []string
+	for name := range earned {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(t, "%s (%s)\n", name, earned[name].Format("Jan 2, 2006"))
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+// how much energy the player has when they're fully rested
+const maxEnergy = 20
+
+// how much energy it takes to climb a piece of furniture
+const climbEffort = 2
+
+// how much energy the player gets back from a rest
+const restRecovery = 4
+
+var tired int         // how much of their energy the player has used up
+var confirming string // the item the player has been asked whether they really want to eat
+
+// energy is how much energy the player has left
+func energy() int {
+	return maxEnergy - tired
+}
+
+// feeling describes how much energy the player has left
+func feeling() string {
+	switch {
+	case energy() == 0:
+		return text("youre-completely-exhausted", "You're completely exhausted.")
+	case energy() <= maxEnergy/4:
+		return text("youre-tired-and-your", "You're tired and your stomach is growling.")
+	case energy() <= maxEnergy/2:
+		return text("you-could-do-with", "You could do with a snack.")
+	default:
+		return text("youre-full-of-energy", "You're full of energy.")
+	}
+}
+
+// exert uses up 'effort' of the player's energy on something strenuous. If
+// they're too tired, it says so and reports false.
+func exert(effort int) bool {
+	if effort == 0 {
+		return true
+	}
+
+	if effort > energy() {
+		say("youre-too-exhausted-for", "You're too exhausted for that. Maybe you should {verb:eat} something, or {verb:rest} for a while.")
+		return false
+	}
+
+	before := feeling()
+	tired += effort
+	if feeling() != before {
+		narrate(feeling())
+	}
+
+	return true
+}
+
+// rest gives the player back a little of their energy, as often as they
+// like, for the price of letting the rest of the house take a turn
+func rest() {
+	if tired == 0 {
+		say("youre-not-tired", "You're not tired.")
+		return
+	}
+
+	tired -= restRecovery
+	if tired < 0 {
+		tired = 0
+	}
+	say("you-sit-down-and", "You sit down for a while and catch your breath.")
+	narrate(feeling())
+}
+
+// needed reports whether 'item' is one of the items needed to win the game,
+// or was made from one
+func needed(item *Item) bool {
+	for _, p := range parts(item) {
+		for _, w := range winningItems {
+			if p == w {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// eatItem searches the player's inventory for an edible item and consumes it.
+// The player must confirm before eating anything needed to fix the shrink ray.
+func eatItem(item string) {
+	val, ok := inventory[item]
+	if !ok {
+		say("is-not-in-your", "%s is not in your backpack.", item)
+		return
+	}
+
+	if !val.IsEdible {
+		say("i-know-youre-hangry", "I know you're hangry. But %s is not food!", item)
+		return
+	}
+
+	if needed(val) {
+		confirming = item
+		say("you-need-the-to", "You need the %s to fix the shrink ray! Are you sure you want to eat it? ('y' or 'n')", item)
+		return
+	}
+
+	eat(val)
+}
+
+// confirmEating carries out the player's answer to whether they really want
+// to eat the item they were asked about
+func confirmEating(action string) {
+	switch action {
+	case "y", "yes":
+		if val, ok := inventory[confirming]; ok {
+			eat(val)
+		}
+		confirming = ""
+	case "n", "no":
+		say("you-put-the-back", "You put the %s back in your backpack.", confirming)
+		confirming = ""
+	default:
+		say("please-type-y-or", "Please type 'y' or 'n'.")
+	}
+}
+
+// eat consumes 'item', giving back some of the player's energy
+func eat(item *Item) {
+	if item.Eaten != "" {
+		narrate(item.Eaten)
+	} else {
+		say("you-eat-the", "You eat the %s.", item.Name)
+	}
+
+	delete(inventory, item.Name)
+	inventoryChanged()
+
+	tired -= item.Nutrition
+	if tired < 0 {
+		tired = 0
+	}
+	narrate(feeling())
+}
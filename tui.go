@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// the full-screen terminal interface: a status line across the top, the
+// story on the left, and what the player is carrying and where they can go on
+// the right
+type tui struct {
+	mu      sync.Mutex
+	story   []string // every line of the story so far
+	scroll  int      // how many lines back from the end of the story is shown
+	status  Status
+	carried []string
+	exits   []Way
+	ed      *lineEditor // what the player is typing
+	over    bool        // the game has ended, and any key leaves
+	closed  bool        // the player pressed Ctrl-C or Ctrl-D
+	lines   *io.PipeWriter
+	leave   chan bool
+}
+
+// playTUI plays a game in the full-screen interface, or line by line if the
+// game isn't being played in a terminal
+func playTUI() {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		playGame()
+		return
+	}
+
+	state, e := term.MakeRaw(fd)
+	if e != nil {
+		log.Fatal(e)
+	}
+	defer term.Restore(fd, state)
+
+	// draw on the alternate screen, so the player's scrollback is left alone
+	fmt.Print("\x1b[?1049h")
+	defer fmt.Print("\x1b[?1049l")
+
+	r, w := io.Pipe()
+	ui := &tui{ed: newLineEditor(), lines: w, leave: make(chan bool)}
+	listeners = []func(Event){ui.hear}
+	in = bufio.NewScanner(r)
+	noteNameable()
+
+	go ui.readKeys(bufio.NewReader(os.Stdin))
+
+	// the screen starts with the game as it stands
+	exitsChanged()
+	inventoryChanged()
+	narrate(text("opening", openingMessage))
+
+	if devMode {
+		stop := watchRooms(nil)
+		defer stop()
+	}
+
+	playing := true
+	for playing && !gameOver && in.Scan() {
+		// the rooms can only be reloaded between commands
+		engineMu.Lock()
+		playing = parseCommand(in.Text())
+		noteNameable()
+		engineMu.Unlock()
+	}
+
+	engineMu.Lock()
+	if gameOver {
+		endGame()
+	}
+	engineMu.Unlock()
+
+	ui.mu.Lock()
+	closed := ui.closed
+	ui.mu.Unlock()
+	if closed {
+		return
+	}
+
+	// nobody is reading what the player types any more
+	r.Close()
+
+	ui.mu.Lock()
+	ui.over = true
+	ui.draw()
+	ui.mu.Unlock()
+	<-ui.leave
+}
+
+// hear keeps what the engine reports, and redraws the screen
+func (ui *tui) hear(e Event) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+
+	switch e.Kind {
+	case StatusChanged:
+		ui.status = e.Status
+	case InventoryChanged:
+		ui.carried = e.Items
+	case ExitsChanged:
+		ui.exits = e.Exits
+	default:
+		ui.story = append(ui.story, renderLines(eventText(e), terminalStyle())...)
+		ui.scroll = 0
+	}
+
+	ui.draw()
+}
+
+// readKeys lets the player edit the line they're typing, and sends it to the
+// engine when they press Enter. Page Up and Page Down scroll through the
+// story.
+func (ui *tui) readKeys(r *bufio.Reader) {
+	for {
+		key, e := readKey(r)
+		if e != nil {
+			key = 4
+		}
+
+		ui.mu.Lock()
+		if ui.over {
+			ui.mu.Unlock()
+			close(ui.leave)
+			return
+		}
+
+		var line string
+		var entered, eof bool
+		switch key {
+		case keyPageUp:
+			ui.scroll += ui.storyHeight() - 1
+		case keyPageDown:
+			ui.scroll -= ui.storyHeight() - 1
+		default:
+			var choices []string
+			line, entered, eof, choices = ui.ed.edit(key)
+			if choices != nil {
+				ui.story = append(ui.story, strings.Join(choices, "   "))
+				ui.scroll = 0
+			}
+		}
+
+		if eof {
+			ui.closed = true
+			ui.mu.Unlock()
+			ui.lines.Close()
+			return
+		}
+
+		if entered {
+			ui.story = append(ui.story, "> "+line)
+			ui.scroll = 0
+		}
+
+		ui.draw()
+		ui.mu.Unlock()
+
+		if entered {
+			fmt.Fprintln(ui.lines, line)
+		}
+	}
+}
+
+// size returns the width and height of the terminal
+func (ui *tui) size() (int, int) {
+	w, h, e := term.GetSize(int(os.Stdout.Fd()))
+	if e != nil || w < 40 || h < 10 {
+		return 80, 24
+	}
+
+	return w, h
+}
+
+// storyHeight is how many lines of the story fit on the screen
+func (ui *tui) storyHeight() int {
+	_, h := ui.size()
+	return h - 3 // the status line, the rule above the input, and the input
+}
+
+// draw redraws the whole screen. The caller must hold ui.mu.
+func (ui *tui) draw() {
+	w, h := ui.size()
+	side := w / 3
+	if side > 30 {
+		side = 30
+	}
+	main := w - side - 1
+	height := h - 3
+
+	var story []string
+	for _, l := range ui.story {
+		story = append(story, wrap(l, main)...)
+	}
+
+	if ui.scroll > len(story)-height {
+		ui.scroll = len(story) - height
+	}
+	if ui.scroll < 0 {
+		ui.scroll = 0
+	}
+	end := len(story) - ui.scroll
+	start := end - height
+	if start < 0 {
+		start = 0
+	}
+	story = story[start:end]
+
+	// the inventory takes the top half of the right side, and the exits the
+	// bottom
+	var panel []string
+	panel = append(panel, "\x1b[1m"+text("inventory-pane", "Inventory")+"\x1b[0m")
+	var carried []string
+	for _, item := range ui.carried {
+		carried = append(carried, localName(item))
+	}
+	sort.Strings(carried)
+	for _, item := range carried {
+		if len(panel) < height/2 {
+			panel = append(panel, " "+item)
+		}
+	}
+	for len(panel) < height/2 {
+		panel = append(panel, "")
+	}
+	panel = append(panel, "\x1b[1m"+text("exits-pane", "Exits")+"\x1b[0m")
+	var exits []string
+	for _, exit := range ui.exits {
+		if exit.Via != "" {
+			exits = append(exits, localName(exit.To)+" ("+localName(exit.Via)+")")
+		} else {
+			exits = append(exits, localName(exit.To))
+		}
+	}
+	sort.Strings(exits)
+	for _, exit := range exits {
+		panel = append(panel, " "+exit)
+	}
+
+	var b strings.Builder
+	b.WriteString("\x1b[?25l\x1b[H")
+
+	s := ui.status
+	status := text("status-line", " %s   Turns: %d   Score: %d   Items: %d/%d", localName(s.Room), s.Turns, s.Score, s.Collected, s.Needed)
+	b.WriteString("\x1b[7m" + pad(status, w) + "\x1b[0m\r\n")
+
+	for i := 0; i < height; i++ {
+		var left, right string
+		if i < len(story) {
+			left = story[i]
+		}
+		if i < len(panel) {
+			right = panel[i]
+		}
+		b.WriteString(pad(left, main) + "\x1b[0m│" + pad(right, side) + "\x1b[0m\r\n")
+	}
+
+	b.WriteString(strings.Repeat("─", w) + "\r\n")
+	if ui.over {
+		b.WriteString(pad(text("press-any-key", "Press any key to leave."), w))
+	} else {
+		b.WriteString(pad("> "+string(ui.ed.line), w))
+		b.WriteString(fmt.Sprintf("\x1b[%d;%dH", h, ui.ed.pos+3))
+	}
+	b.WriteString("\x1b[?25h")
+
+	fmt.Print(b.String())
+}
+
+// wrap breaks 'line' into lines no wider than 'width', between words where it
+// can. Escape sequences take up no room, and a colour cut off at the end of a
+// line carries on at the start of the next.
+func wrap(line string, width int) []string {
+	r := []rune(strings.TrimRight(strings.Replace(line, "\t", "    ", -1), " "))
+
+	var lines []string
+	style := "" // the escape sequence in effect where the last line was cut
+	for {
+		n, end, space := 0, len(r), -1
+		cur, spaceStyle := style, style
+		seq := -1 // where the escape sequence being read started
+		for i, c := range r {
+			if c == '\x1b' {
+				seq = i
+				continue
+			}
+			if seq >= 0 {
+				if c >= 0x40 && c <= 0x7e && c != '[' {
+					if cur = string(r[seq : i+1]); cur == "\x1b[0m" {
+						cur = ""
+					}
+					seq = -1
+				}
+				continue
+			}
+
+			if c == ' ' {
+				space, spaceStyle = i, cur
+			}
+			if n == width {
+				end = i
+				break
+			}
+			n++
+		}
+
+		if end == len(r) {
+			return append(lines, style+string(r))
+		}
+
+		cut, next := end, cur
+		if space > 0 {
+			cut, next = space, spaceStyle
+		}
+		lines = append(lines, style+string(r[:cut]))
+		style = next
+		r = []rune(strings.TrimLeft(string(r[cut:]), " "))
+	}
+}
+
+// pad fills 's' with spaces, or cuts it short, to exactly 'width' characters
+// on the screen. Escape sequences take up no room.
+func pad(s string, width int) string {
+	var b strings.Builder
+	n := 0
+	inEscape := false
+	for _, c := range s {
+		switch {
+		case c == '\x1b':
+			inEscape = true
+		case inEscape:
+			inEscape = c < 0x40 || c > 0x7e || c == '['
+		case n == width:
+			continue
+		default:
+			n++
+		}
+		b.WriteRune(c)
+	}
+
+	return b.String() + strings.Repeat(" ", width-n)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	for _, c := range []struct {
+		line  string
+		width int
+		want  []string
+	}{
+		{"a short line", 20, []string{"a short line"}},
+		{"the quick brown fox", 10, []string{"the quick", "brown fox"}},
+		{"abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"\tindented", 20, []string{"    indented"}},
+		{"\x1b[1mbold words\x1b[0m here", 5, []string{"\x1b[1mbold", "\x1b[1mwords\x1b[0m", "here"}},
+	} {
+		if got := wrap(c.line, c.width); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("wrap(%q, %d) = %q, want %q", c.line, c.width, got, c.want)
+		}
+	}
+}
+
+func TestPad(t *testing.T) {
+	for _, c := range []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"abc", 5, "abc  "},
+		{"abcdef", 3, "abc"},
+		{"\x1b[1mab\x1b[0m", 3, "\x1b[1mab\x1b[0m "},
+		{"ñandú", 5, "ñandú"},
+	} {
+		if got := pad(c.s, c.width); got != c.want {
+			t.Errorf("pad(%q, %d) = %q, want %q", c.s, c.width, got, c.want)
+		}
+	}
+}